@@ -4,14 +4,20 @@ go 1.17
 
 require (
 	github.com/cloudevents/sdk-go/v2 v2.9.0
+	github.com/golang/mock v1.6.0
+	github.com/google/cel-go v0.12.6
 	github.com/kelseyhightower/envconfig v1.4.0
 	github.com/keptn/go-utils v0.14.0
+	google.golang.org/genproto v0.0.0-20220502173005-c8bf987b8c21
 )
 
 require (
-	github.com/golang/mock v1.6.0 // indirect
+	github.com/antlr/antlr4/runtime/Go/antlr v0.0.0-20220418222510-f25a4f6275ed // indirect
 	github.com/google/go-cmp v0.5.6 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
+	github.com/stoewer/go-strcase v1.2.0 // indirect
+	golang.org/x/text v0.3.7 // indirect
+	google.golang.org/protobuf v1.28.0 // indirect
 )
 
 require (
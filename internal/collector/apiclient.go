@@ -0,0 +1,148 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const defaultPageSize = 100
+const defaultMaxEvents = 10000
+const defaultRequestTimeout = 30 * time.Second
+
+type CollectedEvents struct {
+	Events      []cloudevents.Event `json:"events"`
+	NextPageKey string              `json:"nextPageKey,omitempty"`
+	PageSize    int                 `json:"pageSize,omitempty"`
+	TotalCount  int                 `json:"totalCount,omitempty"`
+}
+
+// eventApiClient fetches paginated CollectedEvents documents from Keptn HTTP APIs, retrying
+// transient failures according to its RetryPolicy.
+type eventApiClient struct {
+	apiToken    string
+	pageSize    int
+	maxEvents   int
+	retryPolicy RetryPolicy
+	httpClient  *http.Client
+}
+
+func newEventApiClientFromEnv() eventApiClient {
+	return eventApiClient{
+		apiToken:  os.Getenv("KEPTN_API_TOKEN"),
+		pageSize:  getEnvInt("MONGODB_DATASTORE_PAGE_SIZE", defaultPageSize),
+		maxEvents: getEnvInt("MONGODB_DATASTORE_MAX_EVENTS", defaultMaxEvents),
+		retryPolicy: RetryPolicy{
+			MaxAttempts:    getEnvInt("MONGODB_DATASTORE_RETRY_MAX_ATTEMPTS", defaultRetryMaxAttempts),
+			InitialBackoff: getEnvDuration("MONGODB_DATASTORE_RETRY_INITIAL_BACKOFF", defaultRetryInitialBackoff),
+			MaxBackoff:     getEnvDuration("MONGODB_DATASTORE_RETRY_MAX_BACKOFF", defaultRetryMaxBackoff),
+			Budget:         getEnvDuration("MONGODB_DATASTORE_RETRY_BUDGET", defaultRetryBudget),
+		},
+		httpClient: &http.Client{
+			Timeout: getEnvDuration("MONGODB_DATASTORE_TIMEOUT", defaultRequestTimeout),
+		},
+	}
+}
+
+// getAllEvents follows nextPageKey until every page of u has been fetched. subject describes
+// the query in error messages.
+func (c eventApiClient) getAllEvents(ctx context.Context, u url.URL, subject string) ([]cloudevents.Event, error) {
+	events := []cloudevents.Event{}
+	nextPageKey := ""
+
+	for {
+		page := CollectedEvents{}
+		err := c.retryPolicy.do(ctx, func() error {
+			var err error
+			page, err = c.getEventsPage(ctx, u, nextPageKey)
+			return err
+		})
+		if err != nil {
+			return []cloudevents.Event{}, err
+		}
+
+		events = append(events, page.Events...)
+
+		if c.maxEvents > 0 && len(events) > c.maxEvents {
+			return []cloudevents.Event{}, fmt.Errorf("%w: %s exceeds the maximum of %d events", ErrTooManyEvents, subject, c.maxEvents)
+		}
+
+		isLastPage := page.NextPageKey == "" || page.NextPageKey == "0" || page.NextPageKey == nextPageKey
+		if isLastPage {
+			return events, nil
+		}
+
+		nextPageKey = page.NextPageKey
+	}
+}
+
+func (c eventApiClient) getEventsPage(ctx context.Context, u url.URL, nextPageKey string) (CollectedEvents, error) {
+	query := u.Query()
+
+	if c.pageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(c.pageSize))
+	}
+
+	if nextPageKey != "" {
+		query.Set("nextPageKey", nextPageKey)
+	}
+
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return CollectedEvents{}, err
+	}
+
+	req.Header.Set("x-token", c.apiToken)
+	req.Header.Set("accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return CollectedEvents{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return CollectedEvents{}, fmt.Errorf("reading response of %s failed: %w", u.String(), err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return CollectedEvents{}, newStatusError(u.String(), resp.StatusCode, body)
+	}
+
+	responseBody := CollectedEvents{}
+
+	err = json.Unmarshal(body, &responseBody)
+	if err != nil {
+		return CollectedEvents{}, &DataStoreError{
+			Kind:       ErrMalformedBody,
+			StatusCode: resp.StatusCode,
+			Url:        u.String(),
+			Detail:     err.Error(),
+		}
+	}
+
+	return responseBody, nil
+}
+
+func joinUrlPath(baseUrl string, path string) (url.URL, error) {
+	u, err := url.Parse(baseUrl)
+	if err != nil {
+		return url.URL{}, err
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/") + path
+
+	return *u, nil
+}
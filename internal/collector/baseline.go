@@ -0,0 +1,111 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// Event types a baseline is taken from by default
+const (
+	CollectionFinishedEventType = "sh.keptn.event.collection.finished"
+	EvaluationFinishedEventType = "sh.keptn.event.evaluation.finished"
+)
+
+// Baseline is the evaluation window of the most recent successful run, read from its
+// sh.keptn.event.collection.finished or sh.keptn.event.evaluation.finished event.
+type Baseline struct {
+	Event  *EventEnvelope
+	Window Window
+}
+
+/**
+ * Finds the most recent event of a successful run outside of the excluded context and reads its
+ * evaluation window. Runs are successful if they succeeded with a pass result. Candidates whose
+ * window can't be read are skipped and reported in the returned list. If no baseline is found,
+ * nil is returned.
+ */
+func FindBaseline(events []*EventEnvelope, excludedContext string) (*Baseline, []string) {
+	candidates := []*EventEnvelope{}
+	for _, event := range events {
+		if event.KeptnContext == excludedContext || event.Time.IsZero() {
+			continue
+		}
+
+		if event.Data.Status != keptnv2.StatusSucceeded || event.Data.Result != keptnv2.ResultPass {
+			continue
+		}
+
+		candidates = append(candidates, event)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Time.After(candidates[j].Time)
+	})
+
+	warnings := []string{}
+	for _, candidate := range candidates {
+		window, err := readEvaluationWindow(candidate)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("skipped baseline event %s: %s", candidate.Event.ID(), err.Error()))
+			continue
+		}
+
+		return &Baseline{Event: candidate, Window: window}, warnings
+	}
+
+	return nil, warnings
+}
+
+/**
+ * Reads the evaluation window of a finished event. Collection events report it as evaluation.start
+ * and evaluation.end, evaluation events as evaluation.timeStart and evaluation.timeEnd.
+ */
+func readEvaluationWindow(event *EventEnvelope) (Window, error) {
+	fields, err := event.Fields()
+	if err != nil {
+		return Window{}, fmt.Errorf("failed to decode data: %w", err)
+	}
+
+	evaluation, ok := fields["evaluation"].(map[string]interface{})
+	if !ok {
+		return Window{}, fmt.Errorf("no evaluation found")
+	}
+
+	startField, endField := "start", "end"
+	if _, ok := evaluation["timeStart"]; ok {
+		startField, endField = "timeStart", "timeEnd"
+	}
+
+	start, err := parseEvaluationTime(evaluation, startField)
+	if err != nil {
+		return Window{}, err
+	}
+
+	end, err := parseEvaluationTime(evaluation, endField)
+	if err != nil {
+		return Window{}, err
+	}
+
+	if !end.After(start) {
+		return Window{}, fmt.Errorf("evaluation ends at %s before it starts at %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+
+	return Window{Start: start, End: end}, nil
+}
+
+func parseEvaluationTime(evaluation map[string]interface{}, field string) (time.Time, error) {
+	value, ok := evaluation[field].(string)
+	if !ok || value == "" {
+		return time.Time{}, fmt.Errorf("evaluation.%s not found", field)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("evaluation.%s is no RFC3339 timestamp: \"%s\"", field, value)
+	}
+
+	return timestamp, nil
+}
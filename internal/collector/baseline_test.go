@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func TestFindBaseline(t *testing.T) {
+	start := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+
+	newFinishedEvent := func(id string, keptnContext string, eventType string, offset time.Duration, data string) cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetID(id)
+		event.SetType(eventType)
+		event.SetTime(start.Add(offset))
+		event.SetExtension("shkeptncontext", keptnContext)
+		event.DataEncoded = []byte(data)
+
+		return event
+	}
+
+	collectionPassed := newFinishedEvent("collection-passed", "previous", CollectionFinishedEventType, time.Hour,
+		`{"status": "succeeded", "result": "pass", "evaluation": {"start": "2022-04-07T12:00:00Z", "end": "2022-04-07T12:30:00Z"}}`)
+	evaluationPassed := newFinishedEvent("evaluation-passed", "older", EvaluationFinishedEventType, 0,
+		`{"status": "succeeded", "result": "pass", "evaluation": {"timeStart": "2022-04-07T11:00:00Z", "timeEnd": "2022-04-07T11:30:00Z"}}`)
+	evaluationFailed := newFinishedEvent("evaluation-failed", "failed", EvaluationFinishedEventType, 2*time.Hour,
+		`{"status": "succeeded", "result": "fail", "evaluation": {"timeStart": "2022-04-07T13:00:00Z", "timeEnd": "2022-04-07T13:30:00Z"}}`)
+	collectionErrored := newFinishedEvent("collection-errored", "errored", CollectionFinishedEventType, 3*time.Hour,
+		`{"status": "errored", "result": "pass"}`)
+	current := newFinishedEvent("current", "current", CollectionFinishedEventType, 4*time.Hour,
+		`{"status": "succeeded", "result": "pass", "evaluation": {"start": "2022-04-07T15:00:00Z", "end": "2022-04-07T15:30:00Z"}}`)
+	noEvaluation := newFinishedEvent("no-evaluation", "broken", CollectionFinishedEventType, 5*time.Hour,
+		`{"status": "succeeded", "result": "pass"}`)
+
+	tests := []struct {
+		name             string
+		events           []cloudevents.Event
+		expectedEventId  string
+		expectedWindow   Window
+		expectedWarnings []string
+	}{
+		{
+			name:            "most recent successful run",
+			events:          []cloudevents.Event{evaluationPassed, collectionPassed, evaluationFailed, collectionErrored, current},
+			expectedEventId: "collection-passed",
+			expectedWindow:  Window{Start: start, End: start.Add(30 * time.Minute)},
+		},
+		{
+			name:            "evaluation finished",
+			events:          []cloudevents.Event{evaluationPassed, evaluationFailed},
+			expectedEventId: "evaluation-passed",
+			expectedWindow:  Window{Start: start.Add(-time.Hour), End: start.Add(-30 * time.Minute)},
+		},
+		{
+			name:             "unreadable candidates are skipped",
+			events:           []cloudevents.Event{collectionPassed, noEvaluation},
+			expectedEventId:  "collection-passed",
+			expectedWindow:   Window{Start: start, End: start.Add(30 * time.Minute)},
+			expectedWarnings: []string{"skipped baseline event no-evaluation: no evaluation found"},
+		},
+		{
+			name:   "none",
+			events: []cloudevents.Event{evaluationFailed, current},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			baseline, warnings := FindBaseline(mustDecodeEvents(t, test.events...), "current")
+
+			if test.expectedWarnings == nil {
+				test.expectedWarnings = []string{}
+			}
+			assert.DeepEqual(t, warnings, test.expectedWarnings)
+
+			if test.expectedEventId == "" {
+				assert.Assert(t, baseline == nil)
+				return
+			}
+
+			assert.Assert(t, baseline != nil)
+			assert.Equal(t, baseline.Event.Event.ID(), test.expectedEventId)
+			assert.DeepEqual(t, baseline.Window, test.expectedWindow)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package collector
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const defaultEventCacheTtl = 30 * time.Second
+const defaultEventCacheMaxBytes = 32 * 1024 * 1024
+
+// Rough size of an event's attributes and bookkeeping, added to the size of its data
+const estimatedEventOverheadBytes = 512
+
+type cacheBypassKeyType struct{}
+
+// CacheStats holds the counters of a CachingEventSource.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Entries   int
+	SizeBytes int
+}
+
+// CachingEventSource keeps the results of recent queries against another event source in an LRU
+// cache. Entries are keyed by the filter without its time range and remember the range they were
+// fetched for. Queries within that range, e.g. repeated queries relative to now, are served from
+// the entry. Entries expire after ttl, and the total estimated size of all entries stays below maxBytes.
+type CachingEventSource struct {
+	eventSource EventSource
+	ttl         time.Duration
+	maxBytes    int
+
+	mutex     sync.Mutex
+	entries   map[EventFilter]*list.Element
+	lru       *list.List
+	sizeBytes int
+
+	hits   uint64
+	misses uint64
+}
+
+type cacheEntry struct {
+	key       EventFilter
+	fromTime  time.Time
+	toTime    time.Time
+	events    []cloudevents.Event
+	sizeBytes int
+	expiresAt time.Time
+}
+
+func NewCachingEventSource(eventSource EventSource, ttl time.Duration, maxBytes int) *CachingEventSource {
+	return &CachingEventSource{
+		eventSource: eventSource,
+		ttl:         ttl,
+		maxBytes:    maxBytes,
+		entries:     map[EventFilter]*list.Element{},
+		lru:         list.New(),
+	}
+}
+
+/**
+ * Wraps an event source in a cache configured by EVENT_CACHE_TTL and EVENT_CACHE_MAX_BYTES.
+ * The event source is returned as is if either of them is 0.
+ */
+func NewCachingEventSourceFromEnv(eventSource EventSource) EventSource {
+	ttl := getEnvDuration("EVENT_CACHE_TTL", defaultEventCacheTtl)
+	maxBytes := getEnvInt("EVENT_CACHE_MAX_BYTES", defaultEventCacheMaxBytes)
+
+	if ttl == 0 || maxBytes == 0 {
+		return eventSource
+	}
+
+	return NewCachingEventSource(eventSource, ttl, maxBytes)
+}
+
+/**
+ * Returns a context that makes caching event sources skip cached entries. Fresh results are
+ * still stored in the cache.
+ */
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKeyType{}, true)
+}
+
+func isCacheBypassed(ctx context.Context) bool {
+	isBypassed, _ := ctx.Value(cacheBypassKeyType{}).(bool)
+	return isBypassed
+}
+
+func (s *CachingEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	if !isCacheBypassed(ctx) {
+		if events, ok := s.get(filter); ok {
+			atomic.AddUint64(&s.hits, 1)
+			return events, nil
+		}
+	}
+
+	atomic.AddUint64(&s.misses, 1)
+
+	events, err := s.eventSource.GetEvents(ctx, filter)
+	if err != nil {
+		return events, err
+	}
+
+	s.put(filter, events)
+
+	return append([]cloudevents.Event{}, events...), nil
+}
+
+func (s *CachingEventSource) PushedDownFilters() FilterField {
+	return s.eventSource.PushedDownFilters()
+}
+
+func (s *CachingEventSource) Stats() CacheStats {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&s.hits),
+		Misses:    atomic.LoadUint64(&s.misses),
+		Entries:   s.lru.Len(),
+		SizeBytes: s.sizeBytes,
+	}
+}
+
+func (s *CachingEventSource) get(filter EventFilter) ([]cloudevents.Event, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	element, ok := s.entries[cacheKey(filter)]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		s.remove(element)
+		return nil, false
+	}
+
+	if !entry.covers(filter) {
+		return nil, false
+	}
+
+	s.lru.MoveToFront(element)
+
+	events := []cloudevents.Event{}
+	for _, event := range entry.events {
+		if isWithinTimeRange(event.Time(), filter) {
+			events = append(events, event)
+		}
+	}
+
+	return events, true
+}
+
+/**
+ * Checks whether the time range of the filter lies within the range the entry was fetched for.
+ */
+func (e *cacheEntry) covers(filter EventFilter) bool {
+	isFromCovered := e.fromTime.IsZero() || (!filter.FromTime.IsZero() && !filter.FromTime.Before(e.fromTime))
+	isToCovered := e.toTime.IsZero() || (!filter.ToTime.IsZero() && !filter.ToTime.After(e.toTime))
+
+	return isFromCovered && isToCovered
+}
+
+/**
+ * Returns the filter without its time range. Time values are compared by instant when looking up
+ * entries, so they are kept out of the key.
+ */
+func cacheKey(filter EventFilter) EventFilter {
+	filter.FromTime = time.Time{}
+	filter.ToTime = time.Time{}
+
+	return filter
+}
+
+func (s *CachingEventSource) put(filter EventFilter, events []cloudevents.Event) {
+	sizeBytes := 0
+	for _, event := range events {
+		sizeBytes += len(event.Data()) + estimatedEventOverheadBytes
+	}
+
+	if sizeBytes > s.maxBytes {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := cacheKey(filter)
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+
+	for s.sizeBytes+sizeBytes > s.maxBytes {
+		s.remove(s.lru.Back())
+	}
+
+	s.entries[key] = s.lru.PushFront(&cacheEntry{
+		key:       key,
+		fromTime:  filter.FromTime,
+		toTime:    filter.ToTime,
+		events:    events,
+		sizeBytes: sizeBytes,
+		expiresAt: time.Now().Add(s.ttl),
+	})
+	s.sizeBytes += sizeBytes
+}
+
+func (s *CachingEventSource) remove(element *list.Element) {
+	entry := element.Value.(*cacheEntry)
+
+	s.lru.Remove(element)
+	delete(s.entries, entry.key)
+	s.sizeBytes -= entry.sizeBytes
+}
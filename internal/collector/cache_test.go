@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+type countingEventSource struct {
+	*MemoryEventSource
+	requests int
+}
+
+func (s *countingEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	s.requests++
+	return s.MemoryEventSource.GetEvents(ctx, filter)
+}
+
+func TestCachingEventSource(t *testing.T) {
+	eventSource := &countingEventSource{
+		MemoryEventSource: NewMemoryEventSource(
+			newMockEventInContext("sh.keptn.event.test.started", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+			newMockEventInContext("sh.keptn.event.test.finished", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		),
+	}
+	cache := NewCachingEventSource(eventSource, time.Minute, 1024*1024)
+
+	filter := EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
+
+	events, err := cache.GetEvents(context.Background(), filter)
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+
+	events, err = cache.GetEvents(context.Background(), filter)
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, eventSource.requests, 1)
+
+	_, err = cache.GetEvents(context.Background(), EventFilter{KeptnContext: filter.KeptnContext, Type: "sh.keptn.event.test.started"})
+	assert.NilError(t, err)
+	assert.Equal(t, eventSource.requests, 2)
+
+	_, err = cache.GetEvents(WithCacheBypass(context.Background()), filter)
+	assert.NilError(t, err)
+	assert.Equal(t, eventSource.requests, 3)
+
+	stats := cache.Stats()
+	assert.Equal(t, stats.Hits, uint64(1))
+	assert.Equal(t, stats.Misses, uint64(3))
+	assert.Equal(t, stats.Entries, 2)
+}
+
+func TestCachingEventSourceExpiresEntries(t *testing.T) {
+	eventSource := &countingEventSource{
+		MemoryEventSource: NewMemoryEventSource(newMockEventInContext("sh.keptn.event.test.started", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")),
+	}
+	cache := NewCachingEventSource(eventSource, time.Millisecond, 1024*1024)
+
+	filter := EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
+
+	_, err := cache.GetEvents(context.Background(), filter)
+	assert.NilError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = cache.GetEvents(context.Background(), filter)
+	assert.NilError(t, err)
+	assert.Equal(t, eventSource.requests, 2)
+}
+
+func TestCachingEventSourceEvictsLeastRecentlyUsed(t *testing.T) {
+	eventSource := &countingEventSource{
+		MemoryEventSource: NewMemoryEventSource(
+			newMockEventInContext("sh.keptn.event.test.started", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+			newMockEventInContext("sh.keptn.event.test.started", "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+			newMockEventInContext("sh.keptn.event.test.started", "cccccccc-cccc-cccc-cccc-cccccccccccc"),
+		),
+	}
+
+	// Room for two single event entries
+	cache := NewCachingEventSource(eventSource, time.Minute, 2*estimatedEventOverheadBytes)
+
+	filterA := EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
+	filterB := EventFilter{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}
+	filterC := EventFilter{KeptnContext: "cccccccc-cccc-cccc-cccc-cccccccccccc"}
+
+	for _, filter := range []EventFilter{filterA, filterB, filterA, filterC} {
+		_, err := cache.GetEvents(context.Background(), filter)
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, eventSource.requests, 3)
+
+	// B was evicted, A is still cached
+	_, err := cache.GetEvents(context.Background(), filterA)
+	assert.NilError(t, err)
+	assert.Equal(t, eventSource.requests, 3)
+
+	_, err = cache.GetEvents(context.Background(), filterB)
+	assert.NilError(t, err)
+	assert.Equal(t, eventSource.requests, 4)
+	assert.Equal(t, cache.Stats().SizeBytes, 2*estimatedEventOverheadBytes)
+}
+
+func TestCachingEventSourceServesTimeRangesWithinEntries(t *testing.T) {
+	now := time.Now()
+
+	oldEvent := newMockEventInContext("sh.keptn.event.test.finished", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	oldEvent.SetTime(now.Add(-3 * time.Hour))
+
+	recentEvent := newMockEventInContext("sh.keptn.event.test.finished", "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+	recentEvent.SetTime(now.Add(-time.Hour))
+
+	eventSource := &countingEventSource{
+		MemoryEventSource: NewMemoryEventSource(oldEvent, recentEvent),
+	}
+	cache := NewCachingEventSource(eventSource, time.Minute, 1024*1024)
+
+	// Queries relative to now, e.g. "from": "2h", differ in their start on every call
+	events, err := cache.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.test.finished", FromTime: now.Add(-2 * time.Hour)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+
+	events, err = cache.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.test.finished", FromTime: now.Add(time.Second - 2*time.Hour).In(time.FixedZone("CEST", 2*60*60))})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventSource.requests, 1)
+
+	// Narrower ranges are served from the entry
+	events, err = cache.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.test.finished", FromTime: now.Add(-2 * time.Hour), ToTime: now.Add(-90 * time.Minute)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 0)
+	assert.Equal(t, eventSource.requests, 1)
+
+	// Wider ranges are fetched
+	events, err = cache.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.test.finished", FromTime: now.Add(-4 * time.Hour)})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, eventSource.requests, 2)
+
+	stats := cache.Stats()
+	assert.Equal(t, stats.Hits, uint64(2))
+	assert.Equal(t, stats.Entries, 1)
+}
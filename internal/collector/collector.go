@@ -1,94 +1,74 @@
 package collector
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"os"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
-	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 )
 
 type Collector struct {
-	dataStoreBaseUrl string
-	dataStorePath    string
-	keptnApiToken    string
-	httpClient       *http.Client
+	eventSource EventSource
 }
 
 type CollectorIface interface {
-	GetEventsOfType(eventType string, keptnContext string) ([]cloudevents.Event, error)
+	GetEventsOfType(ctx context.Context, eventType string, keptnContext string) ([]cloudevents.Event, error)
 	// GetTestStartedEvents() ([]cloudevents.Event, error)
 	// GetTestFinishedEvents() ([]cloudevents.Event, error)
-	GetEvents(keptnContext string) ([]cloudevents.Event, error)
-	ParseEvents(events []cloudevents.Event, typeFilter string, stageFilter string) []cloudevents.Event
-	MustParseEvents(events []cloudevents.Event, typeFilter string, stageFilter string) ([]cloudevents.Event, error)
-	CollectExecutionIds(events []cloudevents.Event) ([]string, error)
-	CollectBatchIds(events []cloudevents.Event) ([]string, error)
-	CollectEarliestTime(events []cloudevents.Event, isFloored bool) (time.Time, error)
-	CollectLatestTime(events []cloudevents.Event, isCeiled bool) (time.Time, error)
-}
-
-type CollectedEvents struct {
-	Events []cloudevents.Event `json:"events"`
+	GetEvents(ctx context.Context, keptnContext string) ([]cloudevents.Event, error)
+	GetFilteredEvents(ctx context.Context, filter EventFilter) ([]*EventEnvelope, []DecodeWarning, error)
+	ParseEvents(events []*EventEnvelope, typeFilter string, stageFilter string) []*EventEnvelope
+	MustParseEvents(events []*EventEnvelope, typeFilter string, stageFilter string) ([]*EventEnvelope, error)
+	SelectExecution(events []*EventEnvelope, selector string) ([]*EventEnvelope, error)
+	SelectEvent(events []*EventEnvelope, strategy SelectionStrategy) (*EventEnvelope, error)
+	CollectExecutionIds(events []*EventEnvelope) []string
+	CollectBatchIds(events []*EventEnvelope) []string
+	CollectEarliestTime(events []*EventEnvelope, isFloored bool) (time.Time, error)
+	CollectLatestTime(events []*EventEnvelope, isCeiled bool) (time.Time, error)
 }
 
 type SyntheticTestFinishedEventData struct {
-	SyntheticExecution struct {
-		BatchId      string   `json:"batchId"`
-		ExecutionIds []string `json:"executionIds"`
-	} `json:"syntheticExecution"`
-	Project string `json:"project"`
-	Service string `json:"service"`
-	Stage   string `json:"stage"`
+	SyntheticExecution SyntheticExecution `json:"syntheticExecution"`
+	Project            string             `json:"project"`
+	Service            string             `json:"service"`
+	Stage              string             `json:"stage"`
 }
 
-func (c Collector) GetEventsOfType(eventType string, keptnContext string) ([]cloudevents.Event, error) {
-	u, err := url.Parse(c.dataStoreBaseUrl)
-	if err != nil {
-		return []cloudevents.Event{}, err
-	}
-
-	u.Path = c.dataStorePath
-	query := u.Query()
-	query.Add("keptnContext", keptnContext)
-
-	if eventType != "" {
-		query.Add("type", eventType)
-	}
+func (c Collector) GetEventsOfType(ctx context.Context, eventType string, keptnContext string) ([]cloudevents.Event, error) {
+	return c.eventSource.GetEvents(ctx, EventFilter{
+		KeptnContext: keptnContext,
+		Type:         eventType,
+	})
+}
 
-	u.RawQuery = query.Encode()
+func (c Collector) GetEvents(ctx context.Context, keptnContext string) ([]cloudevents.Event, error) {
+	return c.GetEventsOfType(ctx, "", keptnContext)
+}
 
-	req, _ := http.NewRequest("GET", u.String(), nil)
-	req.Header.Set("x-token", c.keptnApiToken)
-	req.Header.Set("accept", "application/json")
+/**
+ * Fetches all events matching the filter and decodes them into envelopes. Filter fields the event
+ * source supports are sent with the request, the remaining ones are applied client-side.
+ */
+func (c Collector) GetFilteredEvents(ctx context.Context, filter EventFilter) ([]*EventEnvelope, []DecodeWarning, error) {
+	remoteFilter, residualFilter := splitFilter(filter, c.eventSource.PushedDownFilters())
 
-	resp, err := c.httpClient.Do(req)
+	events, err := c.eventSource.GetEvents(ctx, remoteFilter)
 	if err != nil {
-		return []cloudevents.Event{}, err
+		return []*EventEnvelope{}, []DecodeWarning{}, err
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	responseBody := CollectedEvents{}
+	envelopes, warnings := DecodeEvents(events)
 
-	err = json.Unmarshal(body, &responseBody)
-	if err != nil {
-		return []cloudevents.Event{}, err
+	residualFilter.KeptnContext = ""
+	if residualFilter == (EventFilter{}) {
+		return envelopes, warnings, nil
 	}
 
-	return responseBody.Events, nil
+	return filterEnvelopes(envelopes, residualFilter), warnings, nil
 }
 
-func (c Collector) GetEvents(keptnContext string) ([]cloudevents.Event, error) {
-	return c.GetEventsOfType("", keptnContext)
-}
-
-func (c Collector) ParseEvents(events []cloudevents.Event, typeFilter string, stageFilter string) []cloudevents.Event {
+func (c Collector) ParseEvents(events []*EventEnvelope, typeFilter string, stageFilter string) []*EventEnvelope {
 	isFilteredForType := typeFilter != ""
 	isFilteredForStage := stageFilter != ""
 
@@ -96,31 +76,13 @@ func (c Collector) ParseEvents(events []cloudevents.Event, typeFilter string, st
 		return events
 	}
 
-	filteredEvents := []cloudevents.Event{}
-
-	for _, event := range events {
-		if isFilteredForType && typeFilter != event.Type() {
-			continue
-		}
-
-		eventData := keptnv2.EventData{}
-		err := event.DataAs(&eventData)
-		if err != nil {
-			fmt.Println(err.Error())
-			continue
-		}
-
-		if isFilteredForStage && stageFilter != eventData.Stage {
-			continue
-		}
-
-		filteredEvents = append(filteredEvents, event)
-	}
-
-	return filteredEvents
+	return filterEnvelopes(events, EventFilter{
+		Type:  typeFilter,
+		Stage: stageFilter,
+	})
 }
 
-func (c Collector) MustParseEvents(events []cloudevents.Event, typeFilter string, stageFilter string) ([]cloudevents.Event, error) {
+func (c Collector) MustParseEvents(events []*EventEnvelope, typeFilter string, stageFilter string) ([]*EventEnvelope, error) {
 	eventsOfType := c.ParseEvents(events, typeFilter, stageFilter)
 
 	if len(events) < 1 {
@@ -130,59 +92,49 @@ func (c Collector) MustParseEvents(events []cloudevents.Event, typeFilter string
 	return eventsOfType, nil
 }
 
-func (c Collector) CollectExecutionIds(events []cloudevents.Event) ([]string, error) {
+func (c Collector) SelectExecution(events []*EventEnvelope, selector string) ([]*EventEnvelope, error) {
+	return SelectExecution(events, selector)
+}
+
+func (c Collector) SelectEvent(events []*EventEnvelope, strategy SelectionStrategy) (*EventEnvelope, error) {
+	return SelectEvent(events, strategy)
+}
+
+func (c Collector) CollectExecutionIds(events []*EventEnvelope) []string {
 	executionIds := []string{}
 
 	for _, event := range events {
-		eventData := SyntheticTestFinishedEventData{}
-		err := event.DataAs(&eventData)
-		if err != nil {
-			return []string{}, err
-		}
-
-		executionIds = append(executionIds, eventData.SyntheticExecution.ExecutionIds...)
+		executionIds = append(executionIds, event.SyntheticExecution.ExecutionIds...)
 	}
 
-	return executionIds, nil
+	return executionIds
 }
 
-func (c Collector) CollectBatchIds(events []cloudevents.Event) ([]string, error) {
+func (c Collector) CollectBatchIds(events []*EventEnvelope) []string {
 	batchIds := []string{}
 
 	for _, event := range events {
-		eventData := SyntheticTestFinishedEventData{}
-		err := event.DataAs(&eventData)
-		if err != nil {
-			return []string{}, err
-		}
-
-		if eventData.SyntheticExecution.BatchId != "" {
-			batchIds = append(batchIds, eventData.SyntheticExecution.BatchId)
+		if event.SyntheticExecution.BatchId != "" {
+			batchIds = append(batchIds, event.SyntheticExecution.BatchId)
 		}
 	}
 
-	return batchIds, nil
+	return batchIds
 }
 
 func floorSeconds(timestamp time.Time) time.Time {
-	delta := -timestamp.Second()
-	floored := timestamp.Add(time.Second * time.Duration(delta))
-
-	return floored
+	return Rounding{Mode: RoundingFloor, Granularity: time.Minute}.Apply(timestamp)
 }
 
 func ceilSeconds(timestamp time.Time) time.Time {
-	delta := 60 - timestamp.Second()
-	ceiled := timestamp.Add(time.Second * time.Duration(delta))
-
-	return ceiled
+	return Rounding{Mode: RoundingCeil, Granularity: time.Minute}.Apply(timestamp)
 }
 
-func (c Collector) CollectEarliestTime(events []cloudevents.Event, isFloored bool) (time.Time, error) {
+func (c Collector) CollectEarliestTime(events []*EventEnvelope, isFloored bool) (time.Time, error) {
 	earliestTime := time.Time{}
 
 	for _, event := range events {
-		eventTime := event.Context.GetTime()
+		eventTime := event.Time
 
 		if earliestTime.Equal(time.Time{}) || eventTime.Before(earliestTime) {
 			earliestTime = eventTime
@@ -201,11 +153,11 @@ func (c Collector) CollectEarliestTime(events []cloudevents.Event, isFloored boo
 	}
 }
 
-func (c Collector) CollectLatestTime(events []cloudevents.Event, isCeiled bool) (time.Time, error) {
+func (c Collector) CollectLatestTime(events []*EventEnvelope, isCeiled bool) (time.Time, error) {
 	latestTime := time.Time{}
 
 	for _, event := range events {
-		eventTime := event.Context.GetTime()
+		eventTime := event.Time
 
 		if latestTime.Equal(time.Time{}) || eventTime.After(latestTime) {
 			latestTime = eventTime
@@ -224,29 +176,8 @@ func (c Collector) CollectLatestTime(events []cloudevents.Event, isCeiled bool)
 	}
 }
 
-func NewCollector() CollectorIface {
-	dataStoreServiceHost := os.Getenv("MONGODB_DATASTORE_SERVICE_HOST")
-	dataStoreServicePort := os.Getenv("MONGODB_DATASTORE_SERVICE_PORT")
-
-	dataStoreScheme := os.Getenv("MONGODB_DATASTORE_SERVICE_SCHEME")
-	if dataStoreScheme == "" {
-		dataStoreScheme = "http"
-	}
-
-	dataStoreBaseUrl := fmt.Sprintf("%s://%s:%s", dataStoreScheme, dataStoreServiceHost, dataStoreServicePort)
-
-	dataStorePath := os.Getenv("MONGODB_DATASTORE_PATH")
-	if dataStorePath == "" {
-		dataStorePath = "/event"
-	}
-
-	keptnApiToken := os.Getenv("KEPTN_API_TOKEN")
-	httpClient := &http.Client{}
-
+func NewCollector(eventSource EventSource) CollectorIface {
 	return Collector{
-		dataStoreBaseUrl,
-		dataStorePath,
-		keptnApiToken,
-		httpClient,
+		eventSource,
 	}
 }
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -46,6 +47,13 @@ func newMockTestStartedEvent() cloudevents.Event {
 	return mockTestFinishedEvent
 }
 
+func mustDecodeEvents(t *testing.T, events ...cloudevents.Event) []*EventEnvelope {
+	envelopes, warnings := DecodeEvents(events)
+	assert.Equal(t, len(warnings), 0)
+
+	return envelopes
+}
+
 func TestGetEvents(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/event" {
@@ -62,54 +70,56 @@ func TestGetEvents(t *testing.T) {
 	defer server.Close()
 
 	c := Collector{
-		dataStoreBaseUrl: server.URL,
-		dataStorePath:    "/event",
-		keptnApiToken:    "token",
-		httpClient:       server.Client(),
+		eventSource: &DataStoreEventSource{
+			eventApiClient: eventApiClient{
+				apiToken:   "token",
+				httpClient: server.Client(),
+			},
+			baseUrl: server.URL,
+			path:    "/event",
+		},
 	}
 
-	events, err := c.GetEvents("keptnContext")
+	events, err := c.GetEvents(context.Background(), "keptnContext")
 	assert.NilError(t, err)
 	assert.Equal(t, len(events), 2)
 }
 
 func TestParseEventsOfType(t *testing.T) {
-	c := NewCollector()
+	c := NewCollector(NewMemoryEventSource())
 
-	events := c.ParseEvents([]cloudevents.Event{}, "", "")
+	events := c.ParseEvents([]*EventEnvelope{}, "", "")
 	assert.Equal(t, len(events), 0)
 }
 
 func TestMustParseEventsOfType(t *testing.T) {
-	c := NewCollector()
+	c := NewCollector(NewMemoryEventSource())
 
-	events, err := c.MustParseEvents([]cloudevents.Event{}, "sh.keptn.event.test.finished", "")
+	events, err := c.MustParseEvents([]*EventEnvelope{}, "sh.keptn.event.test.finished", "")
 	assert.Error(t, err, "no events found")
 	assert.Equal(t, len(events), 0)
 
-	events, err = c.MustParseEvents([]cloudevents.Event{newMockTestFinishedEvent(), newMockTestStartedEvent()}, "sh.keptn.event.test.finished", "")
+	events, err = c.MustParseEvents(mustDecodeEvents(t, newMockTestFinishedEvent(), newMockTestStartedEvent()), "sh.keptn.event.test.finished", "")
 	assert.NilError(t, err)
 	assert.Equal(t, len(events), 1)
 }
 
 func TestCollectExecutionIds(t *testing.T) {
-	c := NewCollector()
+	c := NewCollector(NewMemoryEventSource())
 
-	executionIds, err := c.CollectExecutionIds([]cloudevents.Event{newMockTestFinishedEvent(), newMockTestStartedEvent()})
-	assert.NilError(t, err)
+	executionIds := c.CollectExecutionIds(mustDecodeEvents(t, newMockTestFinishedEvent(), newMockTestStartedEvent()))
 	assert.Equal(t, len(executionIds), 3)
 }
 
 func TestCollectBatchIds(t *testing.T) {
-	c := NewCollector()
+	c := NewCollector(NewMemoryEventSource())
 
-	batchIds, err := c.CollectBatchIds([]cloudevents.Event{newMockTestFinishedEvent(), newMockTestStartedEvent()})
-	assert.NilError(t, err)
+	batchIds := c.CollectBatchIds(mustDecodeEvents(t, newMockTestFinishedEvent(), newMockTestStartedEvent()))
 	assert.Equal(t, len(batchIds), 1)
 }
 
 func TestCollectEarliestTime(t *testing.T) {
-	collector := NewCollector()
+	collector := NewCollector(NewMemoryEventSource())
 
 	a := cloudevents.NewEvent()
 	timestampA, _ := time.Parse(time.RFC3339, "2022-04-07T12:04:28Z")
@@ -125,11 +135,98 @@ func TestCollectEarliestTime(t *testing.T) {
 	timestampCCeiled, _ := time.Parse(time.RFC3339, "2022-04-07T12:07:00Z")
 	c.SetTime(timestampC)
 
-	earliestTimestamp, err := collector.CollectEarliestTime([]cloudevents.Event{b, a, c}, true)
+	earliestTimestamp, err := collector.CollectEarliestTime(mustDecodeEvents(t, b, a, c), true)
 	assert.NilError(t, err)
 	assert.Equal(t, timestampAFloored, earliestTimestamp)
 
-	latestTimestamp, err := collector.CollectLatestTime([]cloudevents.Event{b, a, c}, true)
+	latestTimestamp, err := collector.CollectLatestTime(mustDecodeEvents(t, b, a, c), true)
 	assert.NilError(t, err)
 	assert.Equal(t, timestampCCeiled, latestTimestamp)
 }
+
+type typeOnlyEventSource struct {
+	*MemoryEventSource
+	receivedFilters []EventFilter
+}
+
+func (s *typeOnlyEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	s.receivedFilters = append(s.receivedFilters, filter)
+	return s.MemoryEventSource.GetEvents(ctx, filter)
+}
+
+func (s *typeOnlyEventSource) PushedDownFilters() FilterField {
+	return FilterByType
+}
+
+func TestGetFilteredEvents(t *testing.T) {
+	stagingEvent := newMockTestFinishedEvent()
+	stagingEvent.SetExtension("shkeptncontext", "keptnContext")
+
+	productionEvent := newMockTestFinishedEvent()
+	productionEvent.SetExtension("shkeptncontext", "keptnContext")
+	productionEvent.DataEncoded = []byte(`{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "production"}`)
+
+	eventSource := &typeOnlyEventSource{
+		MemoryEventSource: NewMemoryEventSource(stagingEvent, productionEvent, newMockTestStartedEvent()),
+	}
+	c := NewCollector(eventSource)
+
+	events, warnings, err := c.GetFilteredEvents(context.Background(), EventFilter{
+		KeptnContext: "keptnContext",
+		Type:         "sh.keptn.event.test.finished",
+		Stage:        "production",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(warnings), 0)
+	assert.Equal(t, len(events), 1)
+	assert.DeepEqual(t, eventSource.receivedFilters, []EventFilter{{
+		KeptnContext: "keptnContext",
+		Type:         "sh.keptn.event.test.finished",
+	}})
+}
+
+func TestGetFilteredEventsReportsUndecodableEvents(t *testing.T) {
+	productionEvent := newMockTestFinishedEvent()
+	productionEvent.SetExtension("shkeptncontext", "keptnContext")
+	productionEvent.DataEncoded = []byte(`{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "production"}`)
+
+	brokenEvent := newMockTestFinishedEvent()
+	brokenEvent.SetID("broken")
+	brokenEvent.SetExtension("shkeptncontext", "keptnContext")
+	brokenEvent.DataEncoded = []byte(`{"stage": `)
+
+	c := NewCollector(NewMemoryEventSource(productionEvent, brokenEvent))
+
+	events, warnings, err := c.GetFilteredEvents(context.Background(), EventFilter{
+		KeptnContext: "keptnContext",
+		Stage:        "production",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, len(warnings), 1)
+	assert.Equal(t, warnings[0].EventId, "broken")
+}
+
+func TestRounding(t *testing.T) {
+	timestamp := time.Date(2022, 4, 7, 12, 4, 28, 0, time.UTC)
+	onBoundary := time.Date(2022, 4, 7, 12, 5, 0, 0, time.UTC)
+
+	tests := []struct {
+		rounding  Rounding
+		timestamp time.Time
+		expected  time.Time
+	}{
+		{rounding: Rounding{Mode: RoundingNone}, timestamp: timestamp, expected: timestamp},
+		{rounding: Rounding{Mode: RoundingFloor, Granularity: time.Minute}, timestamp: timestamp, expected: time.Date(2022, 4, 7, 12, 4, 0, 0, time.UTC)},
+		{rounding: Rounding{Mode: RoundingCeil, Granularity: time.Minute}, timestamp: timestamp, expected: onBoundary},
+		{rounding: Rounding{Mode: RoundingCeil, Granularity: time.Minute}, timestamp: onBoundary, expected: onBoundary},
+		{rounding: Rounding{Mode: RoundingNearest, Granularity: time.Minute}, timestamp: timestamp, expected: time.Date(2022, 4, 7, 12, 4, 0, 0, time.UTC)},
+		{rounding: Rounding{Mode: RoundingNearest, Granularity: 30 * time.Second}, timestamp: timestamp, expected: time.Date(2022, 4, 7, 12, 4, 30, 0, time.UTC)},
+		{rounding: Rounding{Mode: RoundingFloor, Granularity: 5 * time.Minute}, timestamp: timestamp, expected: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)},
+		{rounding: Rounding{Mode: RoundingCeil, Granularity: time.Second}, timestamp: timestamp.Add(time.Millisecond), expected: timestamp.Add(time.Second)},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.rounding.Apply(test.timestamp), test.expected, test.rounding.String())
+	}
+}
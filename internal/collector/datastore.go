@@ -0,0 +1,116 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const keptnApiDataStorePath = "/mongodb-datastore/event"
+
+// Format of event timestamps stored by Keptn, which the datastore compares time filters against
+const dataStoreTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// Resolution of dataStoreTimeFormat
+const dataStoreTimeResolution = time.Millisecond
+
+// DataStoreEventSource fetches events from the mongodb-datastore event endpoint, either in-cluster
+// or through the public Keptn API.
+type DataStoreEventSource struct {
+	eventApiClient
+	baseUrl string
+	path    string
+}
+
+/**
+ * Creates a mongodb-datastore event source configured by the MONGODB_DATASTORE_* environment variables.
+ */
+func NewDataStoreEventSourceFromEnv() *DataStoreEventSource {
+	dataStoreServiceHost := os.Getenv("MONGODB_DATASTORE_SERVICE_HOST")
+	dataStoreServicePort := os.Getenv("MONGODB_DATASTORE_SERVICE_PORT")
+
+	dataStoreScheme := os.Getenv("MONGODB_DATASTORE_SERVICE_SCHEME")
+	if dataStoreScheme == "" {
+		dataStoreScheme = "http"
+	}
+
+	dataStoreBaseUrl := fmt.Sprintf("%s://%s:%s", dataStoreScheme, dataStoreServiceHost, dataStoreServicePort)
+
+	dataStorePath := os.Getenv("MONGODB_DATASTORE_PATH")
+	if dataStorePath == "" {
+		dataStorePath = "/event"
+	}
+
+	return &DataStoreEventSource{
+		eventApiClient: newEventApiClientFromEnv(),
+		baseUrl:        dataStoreBaseUrl,
+		path:           dataStorePath,
+	}
+}
+
+/**
+ * Creates an event source reading the mongodb-datastore through the public Keptn API
+ * at KEPTN_API_ENDPOINT, e.g. https://keptn.example.com/api.
+ */
+func NewKeptnApiEventSourceFromEnv() (*DataStoreEventSource, error) {
+	keptnApiEndpoint := os.Getenv("KEPTN_API_ENDPOINT")
+	if keptnApiEndpoint == "" {
+		return nil, fmt.Errorf("KEPTN_API_ENDPOINT is required for event source \"%s\"", KeptnApiEventSourceName)
+	}
+
+	return &DataStoreEventSource{
+		eventApiClient: newEventApiClientFromEnv(),
+		baseUrl:        keptnApiEndpoint,
+		path:           keptnApiDataStorePath,
+	}, nil
+}
+
+func (s *DataStoreEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	u, err := joinUrlPath(s.baseUrl, s.path)
+	if err != nil {
+		return []cloudevents.Event{}, err
+	}
+
+	query := u.Query()
+
+	if filter.KeptnContext != "" {
+		query.Add("keptnContext", filter.KeptnContext)
+	}
+
+	if filter.Type != "" {
+		query.Add("type", filter.Type)
+	}
+
+	if filter.Stage != "" {
+		query.Add("stage", filter.Stage)
+	}
+
+	if filter.Service != "" {
+		query.Add("service", filter.Service)
+	}
+
+	if filter.Project != "" {
+		query.Add("project", filter.Project)
+	}
+
+	if !filter.FromTime.IsZero() {
+		query.Add("fromTime", filter.FromTime.UTC().Format(dataStoreTimeFormat))
+	}
+
+	// beforeTime is exclusive, while ToTime includes events at its instant
+	if !filter.ToTime.IsZero() {
+		beforeTime := filter.ToTime.UTC().Truncate(dataStoreTimeResolution).Add(dataStoreTimeResolution)
+		query.Add("beforeTime", beforeTime.Format(dataStoreTimeFormat))
+	}
+
+	u.RawQuery = query.Encode()
+
+	return s.getAllEvents(ctx, u, filter.String())
+}
+
+func (s *DataStoreEventSource) PushedDownFilters() FilterField {
+	return FilterByAll
+}
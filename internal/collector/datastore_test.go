@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestGetEventsPaginated(t *testing.T) {
+	requestedPageKeys := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pageSize") != "2" {
+			t.Errorf("Expected pageSize 2, got: %s", r.URL.Query().Get("pageSize"))
+		}
+
+		nextPageKey := r.URL.Query().Get("nextPageKey")
+		requestedPageKeys = append(requestedPageKeys, nextPageKey)
+
+		w.WriteHeader(http.StatusOK)
+		switch nextPageKey {
+		case "":
+			w.Write([]byte(`{"events":[{ "specversion": "1.0" },{ "specversion": "1.0" }],"nextPageKey":"2","pageSize":2,"totalCount":5}`))
+		case "2":
+			w.Write([]byte(`{"events":[{ "specversion": "1.0" },{ "specversion": "1.0" }],"nextPageKey":"4","pageSize":2,"totalCount":5}`))
+		default:
+			w.Write([]byte(`{"events":[{ "specversion": "1.0" }],"nextPageKey":"0","pageSize":1,"totalCount":5}`))
+		}
+	}))
+	defer server.Close()
+
+	c := &DataStoreEventSource{
+		eventApiClient: eventApiClient{
+			apiToken:   "token",
+			pageSize:   2,
+			httpClient: server.Client(),
+		},
+		baseUrl: server.URL,
+		path:    "/event",
+	}
+
+	events, err := c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 5)
+	assert.DeepEqual(t, requestedPageKeys, []string{"", "2", "4"})
+
+	c.maxEvents = 4
+
+	_, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.Error(t, err, "too many events: context keptnContext exceeds the maximum of 4 events")
+	assert.Assert(t, errors.Is(err, ErrTooManyEvents))
+}
+
+func TestGetEventsErrors(t *testing.T) {
+	statusCode := http.StatusOK
+	body := ""
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(statusCode)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	c := &DataStoreEventSource{
+		eventApiClient: eventApiClient{
+			apiToken: "token",
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+			},
+			httpClient: server.Client(),
+		},
+		baseUrl: server.URL,
+		path:    "/event",
+	}
+
+	statusCode, body = http.StatusUnauthorized, `{"code":401,"message":"invalid token"}`
+	_, err := c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.Assert(t, errors.Is(err, ErrUnauthorized))
+	assert.ErrorContains(t, err, "invalid token")
+	assert.Equal(t, requests, 1)
+
+	requests = 0
+	statusCode, body = http.StatusNotFound, ""
+	_, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.Assert(t, errors.Is(err, ErrNotFound))
+	assert.Equal(t, requests, 1)
+
+	requests = 0
+	statusCode, body = http.StatusServiceUnavailable, ""
+	_, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.Assert(t, errors.Is(err, ErrUnavailable))
+	assert.Equal(t, requests, 3)
+
+	requests = 0
+	statusCode, body = http.StatusOK, `{"events":[`
+	_, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.Assert(t, errors.Is(err, ErrMalformedBody))
+	assert.Equal(t, requests, 1)
+}
+
+func TestGetEventsRetriesTransientFailures(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"events":[{ "specversion": "1.0" }]}`))
+	}))
+	defer server.Close()
+
+	c := &DataStoreEventSource{
+		eventApiClient: eventApiClient{
+			apiToken: "token",
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+			},
+			httpClient: server.Client(),
+		},
+		baseUrl: server.URL,
+		path:    "/event",
+	}
+
+	events, err := c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, requests, 3)
+}
+
+func TestGetEventsHonoursContext(t *testing.T) {
+	var requests int32
+	unblock := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case <-r.Context().Done():
+		case <-unblock:
+		}
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	c := &DataStoreEventSource{
+		eventApiClient: eventApiClient{
+			apiToken: "token",
+			retryPolicy: RetryPolicy{
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				MaxBackoff:     time.Millisecond,
+			},
+			httpClient: server.Client(),
+		},
+		baseUrl: server.URL,
+		path:    "/event",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.GetEvents(ctx, EventFilter{KeptnContext: "keptnContext"})
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, atomic.LoadInt32(&requests), int32(1))
+}
+
+func TestKeptnApiEventSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/mongodb-datastore/event" {
+			t.Errorf("Expected to request '/api/mongodb-datastore/event', got: %s", r.URL.Path)
+		}
+
+		if r.URL.Query().Get("type") != "sh.keptn.event.test.finished" {
+			t.Errorf("Expected type filter, got: %s", r.URL.Query().Get("type"))
+		}
+
+		if r.URL.Query().Get("stage") != "staging" {
+			t.Errorf("Expected stage filter, got: %s", r.URL.Query().Get("stage"))
+		}
+
+		if r.URL.Query().Get("fromTime") != "2022-04-07T10:00:00.000Z" {
+			t.Errorf("Expected fromTime filter, got: %s", r.URL.Query().Get("fromTime"))
+		}
+
+		if r.Header.Get("x-token") != "token" {
+			t.Errorf("Expected x-token header, got: %s", r.Header.Get("x-token"))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"events":[{ "specversion": "1.0" }]}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("KEPTN_API_ENDPOINT", server.URL+"/api")
+	t.Setenv("KEPTN_API_TOKEN", "token")
+
+	c, err := NewKeptnApiEventSourceFromEnv()
+	assert.NilError(t, err)
+
+	events, err := c.GetEvents(context.Background(), EventFilter{
+		KeptnContext: "keptnContext",
+		Type:         "sh.keptn.event.test.finished",
+		Stage:        "staging",
+		FromTime:     time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+}
+
+func TestDataStoreEventSourceIncludesToTime(t *testing.T) {
+	tests := []struct {
+		toTime             time.Time
+		expectedBeforeTime string
+	}{
+		{toTime: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC), expectedBeforeTime: "2022-04-07T12:00:00.001Z"},
+		{toTime: time.Date(2022, 4, 7, 12, 0, 0, 999999999, time.UTC), expectedBeforeTime: "2022-04-07T12:00:01.000Z"},
+		{toTime: time.Date(2022, 4, 7, 14, 0, 0, 500, time.FixedZone("CEST", 2*60*60)), expectedBeforeTime: "2022-04-07T12:00:00.001Z"},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("beforeTime") != test.expectedBeforeTime {
+				t.Errorf("Expected beforeTime %s, got: %s", test.expectedBeforeTime, r.URL.Query().Get("beforeTime"))
+			}
+
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"events":[]}`))
+		}))
+
+		c := &DataStoreEventSource{
+			eventApiClient: eventApiClient{
+				httpClient: server.Client(),
+			},
+			baseUrl: server.URL,
+			path:    "/event",
+		}
+
+		_, err := c.GetEvents(context.Background(), EventFilter{KeptnContext: "keptnContext", ToTime: test.toTime})
+		assert.NilError(t, err)
+
+		server.Close()
+	}
+}
@@ -0,0 +1,154 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+type SyntheticExecution struct {
+	BatchId      string   `json:"batchId"`
+	ExecutionIds []string `json:"executionIds"`
+}
+
+// EventEnvelope wraps an event whose payload has been decoded once. Every selection and extraction
+// step works on envelopes instead of decoding the event data again.
+type EventEnvelope struct {
+	Event              cloudevents.Event
+	Data               keptnv2.EventData
+	SyntheticExecution SyntheticExecution
+	KeptnContext       string
+	TriggeredId        string
+	Time               time.Time
+	RawData            []byte
+
+	fieldsOnce sync.Once
+	fields     map[string]interface{}
+	fieldsErr  error
+}
+
+// DecodeWarning describes an event whose payload could not be decoded. The event is still
+// collected, but its payload fields are empty.
+type DecodeWarning struct {
+	EventId   string
+	EventType string
+	Message   string
+}
+
+func (w DecodeWarning) String() string {
+	return fmt.Sprintf("failed to decode data of event %s (%s): %s", w.EventId, w.EventType, w.Message)
+}
+
+type eventPayload struct {
+	keptnv2.EventData
+	SyntheticExecution SyntheticExecution `json:"syntheticExecution"`
+}
+
+/**
+ * Decodes an event into an envelope. If the payload cannot be decoded, the envelope is returned
+ * together with the decoding error.
+ */
+func NewEventEnvelope(event cloudevents.Event) (*EventEnvelope, error) {
+	envelope := &EventEnvelope{
+		Event:        event,
+		KeptnContext: getKeptnContext(event),
+		TriggeredId:  getExtensionString(event, "triggeredid"),
+		Time:         event.Time(),
+		RawData:      event.Data(),
+	}
+
+	payload := eventPayload{}
+	err := event.DataAs(&payload)
+	if err != nil {
+		return envelope, err
+	}
+
+	envelope.Data = payload.EventData
+	envelope.SyntheticExecution = payload.SyntheticExecution
+
+	return envelope, nil
+}
+
+/**
+ * Decodes all events into envelopes. Events whose payload cannot be decoded are kept and
+ * reported as warnings.
+ */
+func DecodeEvents(events []cloudevents.Event) ([]*EventEnvelope, []DecodeWarning) {
+	envelopes := make([]*EventEnvelope, 0, len(events))
+	warnings := []DecodeWarning{}
+
+	for _, event := range events {
+		envelope, err := NewEventEnvelope(event)
+		if err != nil {
+			warnings = append(warnings, DecodeWarning{
+				EventId:   event.ID(),
+				EventType: event.Type(),
+				Message:   err.Error(),
+			})
+		}
+
+		envelopes = append(envelopes, envelope)
+	}
+
+	return envelopes, warnings
+}
+
+/**
+ * Returns the generic representation of the event data. It is decoded on first use and cached.
+ */
+func (e *EventEnvelope) Fields() (map[string]interface{}, error) {
+	e.fieldsOnce.Do(func() {
+		e.fields = map[string]interface{}{}
+		if len(e.RawData) == 0 {
+			return
+		}
+
+		e.fieldsErr = json.Unmarshal(e.RawData, &e.fields)
+	})
+
+	return e.fields, e.fieldsErr
+}
+
+func (e *EventEnvelope) matches(filter EventFilter) bool {
+	if filter.KeptnContext != "" && filter.KeptnContext != e.KeptnContext {
+		return false
+	}
+
+	if !matchesType(filter, e.Event.Type()) {
+		return false
+	}
+
+	if !isWithinTimeRange(e.Time, filter) {
+		return false
+	}
+
+	if filter.Stage != "" && filter.Stage != e.Data.Stage {
+		return false
+	}
+
+	if filter.Service != "" && filter.Service != e.Data.Service {
+		return false
+	}
+
+	if filter.Project != "" && filter.Project != e.Data.Project {
+		return false
+	}
+
+	return true
+}
+
+func filterEnvelopes(envelopes []*EventEnvelope, filter EventFilter) []*EventEnvelope {
+	filteredEnvelopes := []*EventEnvelope{}
+
+	for _, envelope := range envelopes {
+		if envelope.matches(filter) {
+			filteredEnvelopes = append(filteredEnvelopes, envelope)
+		}
+	}
+
+	return filteredEnvelopes
+}
@@ -0,0 +1,38 @@
+package collector
+
+import (
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func TestDecodeEvents(t *testing.T) {
+	testFinishedEvent := newMockTestFinishedEvent()
+	testFinishedEvent.SetID("finished")
+	testFinishedEvent.SetExtension("shkeptncontext", "keptnContext")
+	testFinishedEvent.SetExtension("triggeredid", "triggered")
+
+	malformedEvent := cloudevents.NewEvent()
+	malformedEvent.SetID("malformed")
+	malformedEvent.SetType("sh.keptn.event.test.started")
+	malformedEvent.DataEncoded = []byte(`{"stage": `)
+
+	envelopes, warnings := DecodeEvents([]cloudevents.Event{testFinishedEvent, malformedEvent})
+	assert.Equal(t, len(envelopes), 2)
+	assert.Equal(t, len(warnings), 1)
+	assert.Equal(t, warnings[0].EventId, "malformed")
+
+	assert.Equal(t, envelopes[0].KeptnContext, "keptnContext")
+	assert.Equal(t, envelopes[0].TriggeredId, "triggered")
+	assert.Equal(t, envelopes[0].Data.Stage, "staging")
+	assert.Equal(t, envelopes[0].SyntheticExecution.BatchId, "8602313944601341093")
+
+	fields, err := envelopes[0].Fields()
+	assert.NilError(t, err)
+	assert.Equal(t, fields["service"], "simplenodeservice")
+
+	assert.Equal(t, envelopes[1].Data.Stage, "")
+	_, err = envelopes[1].Fields()
+	assert.Assert(t, err != nil)
+}
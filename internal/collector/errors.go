@@ -0,0 +1,91 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+var (
+	ErrUnauthorized     = errors.New("unauthorized")
+	ErrNotFound         = errors.New("not found")
+	ErrUnavailable      = errors.New("unavailable")
+	ErrMalformedBody    = errors.New("malformed response body")
+	ErrUnexpectedStatus = errors.New("unexpected status")
+	ErrTooManyEvents    = errors.New("too many events")
+)
+
+const maxErrorBodyLength = 256
+
+// DataStoreError describes a failed request against the event datastore. Kind is one of the
+// Err* sentinel errors above and can be matched with errors.Is.
+type DataStoreError struct {
+	Kind       error
+	StatusCode int
+	Url        string
+	Detail     string
+}
+
+func (e *DataStoreError) Error() string {
+	msg := fmt.Sprintf("request to %s failed: %s", e.Url, e.Kind.Error())
+
+	if e.StatusCode != 0 {
+		msg = fmt.Sprintf("%s (status %d)", msg, e.StatusCode)
+	}
+
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Detail)
+	}
+
+	return msg
+}
+
+func (e *DataStoreError) Unwrap() error {
+	return e.Kind
+}
+
+func newStatusError(url string, statusCode int, body []byte) *DataStoreError {
+	kind := ErrUnexpectedStatus
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		kind = ErrUnauthorized
+	case statusCode == http.StatusNotFound:
+		kind = ErrNotFound
+	case statusCode == http.StatusTooManyRequests || statusCode >= 500:
+		kind = ErrUnavailable
+	}
+
+	detail := string(body)
+	if len(detail) > maxErrorBodyLength {
+		detail = detail[:maxErrorBodyLength] + "..."
+	}
+
+	return &DataStoreError{
+		Kind:       kind,
+		StatusCode: statusCode,
+		Url:        url,
+		Detail:     detail,
+	}
+}
+
+// isTransient reports whether a failed request is worth retrying.
+func isTransient(err error) bool {
+	if errors.Is(err, ErrUnavailable) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return false
+}
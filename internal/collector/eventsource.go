@@ -0,0 +1,250 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const (
+	DataStoreEventSourceName          = "mongodb-datastore"
+	KeptnApiEventSourceName           = "keptn-api"
+	ShipyardControllerEventSourceName = "shipyard-controller"
+	FileEventSourceName               = "file"
+)
+
+const defaultShipyardControllerUrl = "http://shipyard-controller:8080"
+
+// EventFilter narrows down the events returned by an EventSource. Empty fields match all events.
+type EventFilter struct {
+	KeptnContext string
+	Type         string
+	// TypePattern is a regular expression event types are matched against, see SetTypes.
+	// It is always applied client-side.
+	TypePattern string
+	Stage       string
+	Service     string
+	Project     string
+	FromTime    time.Time
+	ToTime      time.Time
+}
+
+// FilterField is a set of EventFilter fields, used by event sources to declare which fields they
+// apply server-side.
+type FilterField int
+
+const (
+	FilterByType FilterField = 1 << iota
+	FilterByStage
+	FilterByService
+	FilterByProject
+	FilterByTime
+
+	FilterByAll = FilterByType | FilterByStage | FilterByService | FilterByProject | FilterByTime
+)
+
+// EventSource is a backend Keptn events are fetched from. GetEvents always honours
+// EventFilter.KeptnContext, but only applies the fields returned by PushedDownFilters.
+// The Collector filters the remaining fields client-side.
+type EventSource interface {
+	GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error)
+	PushedDownFilters() FilterField
+}
+
+/**
+ * Creates the event source selected by the EVENT_SOURCE environment variable. The mongodb-datastore
+ * is used if none was provided.
+ */
+func NewEventSource() (EventSource, error) {
+	switch os.Getenv("EVENT_SOURCE") {
+	case "", DataStoreEventSourceName:
+		return NewDataStoreEventSourceFromEnv(), nil
+	case KeptnApiEventSourceName:
+		return NewKeptnApiEventSourceFromEnv()
+	case ShipyardControllerEventSourceName:
+		return NewShipyardControllerEventSourceFromEnv(), nil
+	case FileEventSourceName:
+		return NewFileEventSourceFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown event source \"%s\"", os.Getenv("EVENT_SOURCE"))
+	}
+}
+
+func getKeptnContext(event cloudevents.Event) string {
+	return getExtensionString(event, "shkeptncontext")
+}
+
+func getExtensionString(event cloudevents.Event, name string) string {
+	value, err := event.Context.GetExtension(name)
+	if err != nil {
+		return ""
+	}
+
+	valueString, _ := value.(string)
+	return valueString
+}
+
+/**
+ * Matches the context, type and time range of the event. Stage, service and project require decoding
+ * the event data and are left to the Collector, which reports events it fails to decode as warnings.
+ */
+func matchesFilter(event cloudevents.Event, filter EventFilter) bool {
+	if filter.KeptnContext != "" && filter.KeptnContext != getKeptnContext(event) {
+		return false
+	}
+
+	if !matchesType(filter, event.Type()) {
+		return false
+	}
+
+	return isWithinTimeRange(event.Time(), filter)
+}
+
+/**
+ * Splits a filter into the part an event source applies server-side and the residual part
+ * that has to be applied client-side. Both parts keep the Keptn context.
+ */
+func splitFilter(filter EventFilter, pushedDown FilterField) (EventFilter, EventFilter) {
+	remote := EventFilter{KeptnContext: filter.KeptnContext}
+	residual := EventFilter{KeptnContext: filter.KeptnContext, TypePattern: filter.TypePattern}
+
+	if pushedDown&FilterByType != 0 {
+		remote.Type = filter.Type
+	} else {
+		residual.Type = filter.Type
+	}
+
+	if pushedDown&FilterByStage != 0 {
+		remote.Stage = filter.Stage
+	} else {
+		residual.Stage = filter.Stage
+	}
+
+	if pushedDown&FilterByService != 0 {
+		remote.Service = filter.Service
+	} else {
+		residual.Service = filter.Service
+	}
+
+	if pushedDown&FilterByProject != 0 {
+		remote.Project = filter.Project
+	} else {
+		residual.Project = filter.Project
+	}
+
+	if pushedDown&FilterByTime != 0 {
+		remote.FromTime = filter.FromTime
+		remote.ToTime = filter.ToTime
+	} else {
+		residual.FromTime = filter.FromTime
+		residual.ToTime = filter.ToTime
+	}
+
+	return remote, residual
+}
+
+func filterEvents(events []cloudevents.Event, filter EventFilter) []cloudevents.Event {
+	filteredEvents := []cloudevents.Event{}
+
+	for _, event := range events {
+		if matchesFilter(event, filter) {
+			filteredEvents = append(filteredEvents, event)
+		}
+	}
+
+	return filteredEvents
+}
+
+func isWithinTimeRange(timestamp time.Time, filter EventFilter) bool {
+	if !filter.FromTime.IsZero() && timestamp.Before(filter.FromTime) {
+		return false
+	}
+
+	if !filter.ToTime.IsZero() && timestamp.After(filter.ToTime) {
+		return false
+	}
+
+	return true
+}
+
+/**
+ * Describes the filter for log and error messages, e.g.
+ * context 0dc1538a-2550-49b5-8319-30d57a83519f, type "sh.keptn.event.test.finished"
+ */
+func (f EventFilter) String() string {
+	parts := []string{}
+
+	if f.KeptnContext != "" {
+		parts = append(parts, fmt.Sprintf("context %s", f.KeptnContext))
+	}
+
+	if f.Project != "" {
+		parts = append(parts, fmt.Sprintf("project \"%s\"", f.Project))
+	}
+
+	if f.Stage != "" {
+		parts = append(parts, fmt.Sprintf("stage \"%s\"", f.Stage))
+	}
+
+	if f.Service != "" {
+		parts = append(parts, fmt.Sprintf("service \"%s\"", f.Service))
+	}
+
+	if f.Type != "" {
+		parts = append(parts, fmt.Sprintf("type \"%s\"", f.Type))
+	}
+
+	if f.TypePattern != "" {
+		parts = append(parts, fmt.Sprintf("type matching \"%s\"", f.TypePattern))
+	}
+
+	if !f.FromTime.IsZero() {
+		parts = append(parts, fmt.Sprintf("from %s", f.FromTime.Format(time.RFC3339)))
+	}
+
+	if !f.ToTime.IsZero() {
+		parts = append(parts, fmt.Sprintf("to %s", f.ToTime.Format(time.RFC3339)))
+	}
+
+	if len(parts) == 0 {
+		return "all events"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid value \"%s\" for %s, using %d", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		log.Printf("Ignoring invalid value \"%s\" for %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return parsed
+}
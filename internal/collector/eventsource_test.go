@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func newMockEventInContext(eventType string, keptnContext string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetType(eventType)
+	event.SetExtension("shkeptncontext", keptnContext)
+
+	return event
+}
+
+func TestNewEventSource(t *testing.T) {
+	eventSource, err := NewEventSource()
+	assert.NilError(t, err)
+	_, ok := eventSource.(*DataStoreEventSource)
+	assert.Assert(t, ok)
+
+	t.Setenv("EVENT_SOURCE", ShipyardControllerEventSourceName)
+	eventSource, err = NewEventSource()
+	assert.NilError(t, err)
+	_, ok = eventSource.(*ShipyardControllerEventSource)
+	assert.Assert(t, ok)
+
+	t.Setenv("EVENT_SOURCE", KeptnApiEventSourceName)
+	_, err = NewEventSource()
+	assert.Error(t, err, "KEPTN_API_ENDPOINT is required for event source \"keptn-api\"")
+
+	t.Setenv("EVENT_SOURCE", "memory")
+	_, err = NewEventSource()
+	assert.Error(t, err, "unknown event source \"memory\"")
+
+	t.Setenv("EVENT_SOURCE", "unknown")
+	_, err = NewEventSource()
+	assert.Error(t, err, "unknown event source \"unknown\"")
+}
+
+func TestMemoryEventSource(t *testing.T) {
+	eventSource := NewMemoryEventSource(
+		newMockEventInContext("sh.keptn.event.test.started", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		newMockEventInContext("sh.keptn.event.test.finished", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+	)
+	eventSource.AddEvents(newMockEventInContext("sh.keptn.event.test.finished", "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"))
+
+	events, err := eventSource.GetEvents(context.Background(), EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+
+	events, err = eventSource.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.test.finished"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+
+	events, err = eventSource.GetEvents(context.Background(), EventFilter{
+		KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+		Type:         "sh.keptn.event.test.started",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 0)
+}
+
+func TestMemoryEventSourceTimeRange(t *testing.T) {
+	earlyEvent := newMockEventInContext("sh.keptn.event.test.finished", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	earlyEvent.SetTime(time.Date(2022, 4, 7, 9, 0, 0, 0, time.UTC))
+
+	lateEvent := newMockEventInContext("sh.keptn.event.test.finished", "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+	lateEvent.SetTime(time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC))
+
+	eventSource := NewMemoryEventSource(earlyEvent, lateEvent)
+
+	events, err := eventSource.GetEvents(context.Background(), EventFilter{
+		Type:     "sh.keptn.event.test.finished",
+		FromTime: time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC),
+		ToTime:   time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, getKeptnContext(events[0]), "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+}
+
+func TestEventFilterString(t *testing.T) {
+	assert.Equal(t, EventFilter{}.String(), "all events")
+	assert.Equal(t, EventFilter{
+		Project:  "simplenode-gitlab",
+		Stage:    "staging",
+		Type:     "sh.keptn.event.test.finished",
+		FromTime: time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC),
+	}.String(), `project "simplenode-gitlab", stage "staging", type "sh.keptn.event.test.finished", from 2022-04-07T10:00:00Z`)
+}
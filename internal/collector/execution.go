@@ -0,0 +1,108 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Selectors picking an execution by its position. Any other selector is treated as an event ID.
+const (
+	ExecutionFirst  = "first"
+	ExecutionLatest = "latest"
+)
+
+// Execution is a triggered → started → finished chain. All events of an execution share the
+// ID of the triggered event, either as their own ID or as their triggeredid.
+type Execution struct {
+	TriggeredId string
+	Start       time.Time
+	Events      []*EventEnvelope
+}
+
+/**
+ * Returns the triggered event ID of the chain an event belongs to. Events that are neither
+ * triggered events nor reference one don't belong to any chain.
+ */
+func getTriggeredId(envelope *EventEnvelope) string {
+	if envelope.TriggeredId != "" {
+		return envelope.TriggeredId
+	}
+
+	if strings.HasSuffix(envelope.Event.Type(), ".triggered") {
+		return envelope.Event.ID()
+	}
+
+	return ""
+}
+
+/**
+ * Rebuilds the execution chains of the events via their triggeredid. Executions are ordered by
+ * their earliest event.
+ */
+func GroupExecutions(events []*EventEnvelope) []*Execution {
+	executions := []*Execution{}
+	executionsByTriggeredId := map[string]*Execution{}
+
+	for _, event := range events {
+		triggeredId := getTriggeredId(event)
+		if triggeredId == "" {
+			continue
+		}
+
+		execution, ok := executionsByTriggeredId[triggeredId]
+		if !ok {
+			execution = &Execution{
+				TriggeredId: triggeredId,
+				Start:       event.Time,
+			}
+			executionsByTriggeredId[triggeredId] = execution
+			executions = append(executions, execution)
+		}
+
+		if event.Time.Before(execution.Start) {
+			execution.Start = event.Time
+		}
+
+		execution.Events = append(execution.Events, event)
+	}
+
+	sort.SliceStable(executions, func(i, j int) bool {
+		return executions[i].Start.Before(executions[j].Start)
+	})
+
+	return executions
+}
+
+/**
+ * Returns the events of a single execution. The selector is either first, latest or the ID of an
+ * event of the execution, including its triggered event.
+ */
+func SelectExecution(events []*EventEnvelope, selector string) ([]*EventEnvelope, error) {
+	executions := GroupExecutions(events)
+	if len(executions) == 0 {
+		return []*EventEnvelope{}, fmt.Errorf("no executions found")
+	}
+
+	switch selector {
+	case ExecutionFirst:
+		return executions[0].Events, nil
+	case ExecutionLatest:
+		return executions[len(executions)-1].Events, nil
+	}
+
+	for _, execution := range executions {
+		if execution.TriggeredId == selector {
+			return execution.Events, nil
+		}
+
+		for _, event := range execution.Events {
+			if event.Event.ID() == selector {
+				return execution.Events, nil
+			}
+		}
+	}
+
+	return []*EventEnvelope{}, fmt.Errorf("no execution contains event %s", selector)
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func newMockChainEvent(id string, eventType string, triggeredId string, timestamp time.Time) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetType(eventType)
+	event.SetTime(timestamp)
+	if triggeredId != "" {
+		event.SetExtension("triggeredid", triggeredId)
+	}
+
+	return event
+}
+
+func TestSelectExecution(t *testing.T) {
+	events := mustDecodeEvents(t,
+		newMockChainEvent("retry-started", "sh.keptn.event.test.started", "retry", time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)),
+		newMockChainEvent("first", "sh.keptn.event.test.triggered", "", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC)),
+		newMockChainEvent("first-started", "sh.keptn.event.test.started", "first", time.Date(2022, 4, 7, 10, 1, 0, 0, time.UTC)),
+		newMockChainEvent("first-finished", "sh.keptn.event.test.finished", "first", time.Date(2022, 4, 7, 10, 30, 0, 0, time.UTC)),
+		newMockChainEvent("unrelated", "sh.keptn.event.get-sli.started", "", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC)),
+		newMockChainEvent("retry-finished", "sh.keptn.event.test.finished", "retry", time.Date(2022, 4, 7, 12, 30, 0, 0, time.UTC)),
+	)
+
+	executions := GroupExecutions(events)
+	assert.Equal(t, len(executions), 2)
+	assert.Equal(t, executions[0].TriggeredId, "first")
+	assert.Equal(t, executions[1].TriggeredId, "retry")
+	assert.Equal(t, executions[1].Start, time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC))
+
+	selected, err := SelectExecution(events, ExecutionFirst)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 3)
+
+	selected, err = SelectExecution(events, ExecutionLatest)
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 2)
+	assert.Equal(t, selected[0].Event.ID(), "retry-started")
+
+	selected, err = SelectExecution(events, "first-finished")
+	assert.NilError(t, err)
+	assert.Equal(t, selected[0].Event.ID(), "first")
+
+	selected, err = SelectExecution(events, "retry")
+	assert.NilError(t, err)
+	assert.Equal(t, len(selected), 2)
+
+	_, err = SelectExecution(events, "unrelated")
+	assert.Error(t, err, "no execution contains event unrelated")
+
+	_, err = SelectExecution(mustDecodeEvents(t), ExecutionLatest)
+	assert.Error(t, err, "no executions found")
+}
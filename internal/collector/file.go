@@ -0,0 +1,159 @@
+package collector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const maxJsonLineLength = 16 * 1024 * 1024
+
+// FileEventSource serves events read from a directory of JSON files or from a JSONL export.
+// Every JSON document is either a single event or a CollectedEvents document. Events are
+// indexed by their shkeptncontext when the source is created.
+type FileEventSource struct {
+	events          []cloudevents.Event
+	eventsByContext map[string][]cloudevents.Event
+}
+
+/**
+ * Creates a file event source for the directory or JSONL file at EVENT_SOURCE_PATH.
+ */
+func NewFileEventSourceFromEnv() (*FileEventSource, error) {
+	path := os.Getenv("EVENT_SOURCE_PATH")
+	if path == "" {
+		return nil, fmt.Errorf("EVENT_SOURCE_PATH is required for event source \"%s\"", FileEventSourceName)
+	}
+
+	return NewFileEventSource(path)
+}
+
+/**
+ * Creates a file event source for a directory of *.json files or a single *.json or *.jsonl file.
+ */
+func NewFileEventSource(path string) (*FileEventSource, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if fileInfo.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	s := &FileEventSource{
+		events:          []cloudevents.Event{},
+		eventsByContext: map[string][]cloudevents.Event{},
+	}
+
+	for _, file := range files {
+		events, err := readEventFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			keptnContext := getKeptnContext(event)
+			s.eventsByContext[keptnContext] = append(s.eventsByContext[keptnContext], event)
+		}
+		s.events = append(s.events, events...)
+	}
+
+	return s, nil
+}
+
+func (s *FileEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return []cloudevents.Event{}, err
+	}
+
+	if filter.KeptnContext != "" {
+		return filterEvents(s.eventsByContext[filter.KeptnContext], filter), nil
+	}
+
+	return filterEvents(s.events, filter), nil
+}
+
+func readEventFile(file string) ([]cloudevents.Event, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	isJsonLines := strings.HasSuffix(file, ".jsonl") || strings.HasSuffix(file, ".ndjson")
+	if !isJsonLines {
+		events, err := decodeEventDocument(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		return events, nil
+	}
+
+	events := []cloudevents.Event{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJsonLineLength)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		document := bytes.TrimSpace(scanner.Bytes())
+		if len(document) == 0 {
+			continue
+		}
+
+		lineEvents, err := decodeEventDocument(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s, line %d: %w", file, line, err)
+		}
+		events = append(events, lineEvents...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	return events, nil
+}
+
+func decodeEventDocument(document []byte) ([]cloudevents.Event, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, err
+	}
+
+	_, hasEvents := fields["events"]
+	_, hasSpecVersion := fields["specversion"]
+
+	if hasEvents && !hasSpecVersion {
+		collectedEvents := CollectedEvents{}
+		if err := json.Unmarshal(document, &collectedEvents); err != nil {
+			return nil, err
+		}
+		return collectedEvents.Events, nil
+	}
+
+	event := cloudevents.Event{}
+	if err := json.Unmarshal(document, &event); err != nil {
+		return nil, err
+	}
+
+	return []cloudevents.Event{event}, nil
+}
+
+func (s *FileEventSource) PushedDownFilters() FilterField {
+	return FilterByType | FilterByTime
+}
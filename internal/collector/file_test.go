@@ -0,0 +1,52 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestFileEventSourceFromDirectory(t *testing.T) {
+	eventSource, err := NewFileEventSource("../../test-events")
+	assert.NilError(t, err)
+
+	events, err := eventSource.GetEvents(context.Background(), EventFilter{KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 4)
+
+	events, err = eventSource.GetEvents(context.Background(), EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Type:         "sh.keptn.event.test.triggered",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+
+	events, err = eventSource.GetEvents(context.Background(), EventFilter{Type: "sh.keptn.event.get-sli.triggered"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+}
+
+func TestFileEventSourceFromJsonLines(t *testing.T) {
+	exportFile := filepath.Join(t.TempDir(), "export.jsonl")
+	err := os.WriteFile(exportFile, []byte(`{"specversion":"1.0","id":"1","source":"test","type":"sh.keptn.event.test.started","shkeptncontext":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}
+
+{"events":[{"specversion":"1.0","id":"2","source":"test","type":"sh.keptn.event.test.finished","shkeptncontext":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"},{"specversion":"1.0","id":"3","source":"test","type":"sh.keptn.event.test.finished","shkeptncontext":"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}]}
+`), 0600)
+	assert.NilError(t, err)
+
+	eventSource, err := NewFileEventSource(exportFile)
+	assert.NilError(t, err)
+
+	events, err := eventSource.GetEvents(context.Background(), EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+
+	err = os.WriteFile(exportFile, []byte("{\"specversion\":\"1.0\"}\nnot json\n"), 0600)
+	assert.NilError(t, err)
+
+	_, err = NewFileEventSource(exportFile)
+	assert.ErrorContains(t, err, "line 2")
+}
@@ -0,0 +1,43 @@
+package collector
+
+import (
+	"context"
+	"sync"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// MemoryEventSource serves events held in memory, e.g. in tests. It can't be selected by EVENT_SOURCE
+// since nothing fills it at runtime. It is safe for concurrent use.
+type MemoryEventSource struct {
+	mutex  sync.RWMutex
+	events []cloudevents.Event
+}
+
+func NewMemoryEventSource(events ...cloudevents.Event) *MemoryEventSource {
+	return &MemoryEventSource{
+		events: events,
+	}
+}
+
+func (s *MemoryEventSource) AddEvents(events ...cloudevents.Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.events = append(s.events, events...)
+}
+
+func (s *MemoryEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return []cloudevents.Event{}, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return filterEvents(s.events, filter), nil
+}
+
+func (s *MemoryEventSource) PushedDownFilters() FilterField {
+	return FilterByType | FilterByTime
+}
@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+const defaultRetryMaxAttempts = 3
+const defaultRetryInitialBackoff = 500 * time.Millisecond
+const defaultRetryMaxBackoff = 5 * time.Second
+const defaultRetryBudget = 30 * time.Second
+
+// RetryPolicy controls how transient datastore failures are retried. Backoff grows exponentially
+// from InitialBackoff up to MaxBackoff with full jitter. No further attempt is made once Budget
+// would be exceeded or the context is done.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Budget         time.Duration
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 0; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	startedAt := time.Now()
+	attempt := 0
+
+	for {
+		err := fn()
+		attempt++
+
+		if err == nil || ctx.Err() != nil || !isTransient(err) || attempt >= p.MaxAttempts {
+			return err
+		}
+
+		backoff := p.backoff(attempt - 1)
+		if p.Budget > 0 && time.Since(startedAt)+backoff > p.Budget {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// Modes timestamps are rounded to a granularity by.
+const (
+	RoundingNone    = "none"
+	RoundingFloor   = "floor"
+	RoundingCeil    = "ceil"
+	RoundingNearest = "nearest"
+)
+
+type Rounding struct {
+	Mode        string
+	Granularity time.Duration
+}
+
+/**
+ * Rounds the timestamp to a multiple of the granularity. Timestamps already sitting on a multiple
+ * are returned as is.
+ */
+func (r Rounding) Apply(timestamp time.Time) time.Time {
+	if r.Mode == RoundingNone || r.Mode == "" || r.Granularity <= 0 {
+		return timestamp
+	}
+
+	truncated := timestamp.Truncate(r.Granularity)
+
+	switch r.Mode {
+	case RoundingFloor:
+		return truncated
+	case RoundingCeil:
+		if truncated.Equal(timestamp) {
+			return timestamp
+		}
+
+		return truncated.Add(r.Granularity)
+	case RoundingNearest:
+		return timestamp.Round(r.Granularity)
+	default:
+		return timestamp
+	}
+}
+
+/**
+ * Describes the rounding for the finished event, e.g. floor 1m0s
+ */
+func (r Rounding) String() string {
+	if r.Mode == RoundingNone || r.Mode == "" {
+		return RoundingNone
+	}
+
+	return fmt.Sprintf("%s %s", r.Mode, r.Granularity)
+}
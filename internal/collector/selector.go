@@ -0,0 +1,77 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/expression"
+)
+
+// Variables event selection expressions can reference. Event data is available as a generic map,
+// e.g. data.result or data.labels["team"].
+var eventExpressionVariables = []string{"id", "source", "type", "time", "shkeptncontext", "triggeredid", "data"}
+
+// EventExpression is a compiled expression selecting events, e.g.
+// data.result == "pass" && source == "jmeter-service".
+type EventExpression struct {
+	program *expression.Program
+}
+
+func CompileEventExpression(source string) (*EventExpression, error) {
+	program, err := expression.Compile(source, eventExpressionVariables...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EventExpression{program: program}, nil
+}
+
+func (e *EventExpression) String() string {
+	return e.program.String()
+}
+
+/**
+ * Returns the events the expression evaluates to true for. Events the expression can't be
+ * evaluated for are not selected and reported as warnings.
+ */
+func (e *EventExpression) Filter(events []*EventEnvelope) ([]*EventEnvelope, []string) {
+	selectedEvents := []*EventEnvelope{}
+	warnings := []string{}
+
+	for _, event := range events {
+		isSelected, err := e.program.Evaluate(event.expressionVariables())
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to evaluate expression %s for event %s (%s): %s", e.String(), event.Event.ID(), event.Event.Type(), err.Error()))
+			continue
+		}
+
+		if isSelected {
+			selectedEvents = append(selectedEvents, event)
+		}
+	}
+
+	return selectedEvents, warnings
+}
+
+func (e *EventEnvelope) expressionVariables() map[string]interface{} {
+	variables := map[string]interface{}{
+		"id":             e.Event.ID(),
+		"source":         e.Event.Source(),
+		"type":           e.Event.Type(),
+		"time":           nil,
+		"shkeptncontext": e.KeptnContext,
+		"triggeredid":    e.TriggeredId,
+		"data":           nil,
+	}
+
+	if !e.Time.IsZero() {
+		variables["time"] = e.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	fields, err := e.Fields()
+	if err == nil {
+		variables["data"] = fields
+	}
+
+	return variables
+}
@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
+)
+
+func TestEventExpressionFilter(t *testing.T) {
+	passedEvent := newMockTestFinishedEvent()
+	passedEvent.SetID("passed")
+	passedEvent.SetSource("jmeter-service")
+	passedEvent.DataEncoded = []byte(`{"result": "pass", "labels": {"team": "checkout"}, "test": {"name": "load"}}`)
+
+	failedEvent := newMockTestFinishedEvent()
+	failedEvent.SetID("failed")
+	failedEvent.SetSource("jmeter-service")
+	failedEvent.DataEncoded = []byte(`{"result": "fail", "labels": {"team": "checkout"}, "test": {"name": "load"}}`)
+
+	otherEvent := newMockTestFinishedEvent()
+	otherEvent.SetID("other")
+	otherEvent.SetSource("locust-service")
+	otherEvent.DataEncoded = []byte(`{"result": "pass", "test": "load"}`)
+
+	events := mustDecodeEvents(t, passedEvent, failedEvent, otherEvent)
+
+	expression, err := CompileEventExpression(`source == "jmeter-service" && data.result == "pass" && data.labels.team == "checkout"`)
+	assert.NilError(t, err)
+
+	selected, warnings := expression.Filter(events)
+	assert.Equal(t, len(warnings), 0)
+	assert.Equal(t, len(selected), 1)
+	assert.Equal(t, selected[0].Event.ID(), "passed")
+
+	expression, err = CompileEventExpression(`data.test.name == "load"`)
+	assert.NilError(t, err)
+
+	selected, warnings = expression.Filter(events)
+	assert.Equal(t, len(selected), 2)
+	assert.Equal(t, len(warnings), 1)
+	assert.Assert(t, cmp.Contains(warnings[0], "event other (sh.keptn.event.test.finished)"))
+
+	_, err = CompileEventExpression(`result == "pass"`)
+	assert.Error(t, err, "undeclared reference to 'result' at position 0")
+}
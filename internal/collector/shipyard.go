@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const shipyardControllerTriggeredEventsPath = "/v1/event/triggered/"
+
+// ShipyardControllerEventSource fetches events from the shipyard-controller event API. The API only
+// exposes open .triggered events of a given type, so filters without a .triggered event type are
+// served by the fallback event source.
+type ShipyardControllerEventSource struct {
+	eventApiClient
+	baseUrl  string
+	fallback EventSource
+}
+
+/**
+ * Creates a shipyard-controller event source for SHIPYARD_CONTROLLER_URL. The in-cluster
+ * shipyard-controller service is used if none was provided. Events the shipyard-controller doesn't
+ * expose are fetched from the mongodb-datastore.
+ */
+func NewShipyardControllerEventSourceFromEnv() *ShipyardControllerEventSource {
+	shipyardControllerUrl := os.Getenv("SHIPYARD_CONTROLLER_URL")
+	if shipyardControllerUrl == "" {
+		shipyardControllerUrl = defaultShipyardControllerUrl
+	}
+
+	return &ShipyardControllerEventSource{
+		eventApiClient: newEventApiClientFromEnv(),
+		baseUrl:        shipyardControllerUrl,
+		fallback:       NewDataStoreEventSourceFromEnv(),
+	}
+}
+
+func (s *ShipyardControllerEventSource) GetEvents(ctx context.Context, filter EventFilter) ([]cloudevents.Event, error) {
+	if !strings.HasSuffix(filter.Type, ".triggered") {
+		if s.fallback == nil {
+			return []cloudevents.Event{}, fmt.Errorf("event source \"%s\" requires a .triggered event type", ShipyardControllerEventSourceName)
+		}
+
+		return s.fallback.GetEvents(ctx, filter)
+	}
+
+	u, err := joinUrlPath(s.baseUrl, shipyardControllerTriggeredEventsPath+filter.Type)
+	if err != nil {
+		return []cloudevents.Event{}, err
+	}
+
+	query := u.Query()
+
+	if filter.Stage != "" {
+		query.Add("stage", filter.Stage)
+	}
+
+	if filter.Service != "" {
+		query.Add("service", filter.Service)
+	}
+
+	if filter.Project != "" {
+		query.Add("project", filter.Project)
+	}
+
+	u.RawQuery = query.Encode()
+
+	events, err := s.getAllEvents(ctx, u, filter.String())
+	if err != nil {
+		return []cloudevents.Event{}, err
+	}
+
+	// The triggered events endpoint does not filter by context
+	return filterEvents(events, EventFilter{KeptnContext: filter.KeptnContext}), nil
+}
+
+func (s *ShipyardControllerEventSource) PushedDownFilters() FilterField {
+	return FilterByType | FilterByStage | FilterByService | FilterByProject
+}
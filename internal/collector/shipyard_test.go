@@ -0,0 +1,59 @@
+package collector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestShipyardControllerEventSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/event/triggered/sh.keptn.event.test.triggered" {
+			t.Errorf("Expected to request '/v1/event/triggered/sh.keptn.event.test.triggered', got: %s", r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"events":[
+			{ "specversion": "1.0", "id": "1", "source": "test", "type": "sh.keptn.event.test.triggered", "shkeptncontext": "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa" },
+			{ "specversion": "1.0", "id": "2", "source": "test", "type": "sh.keptn.event.test.triggered", "shkeptncontext": "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb" }
+		]}`))
+	}))
+	defer server.Close()
+
+	c := &ShipyardControllerEventSource{
+		eventApiClient: eventApiClient{
+			httpClient: server.Client(),
+		},
+		baseUrl: server.URL,
+	}
+
+	events, err := c.GetEvents(context.Background(), EventFilter{
+		KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		Type:         "sh.keptn.event.test.triggered",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+
+	_, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"})
+	assert.Error(t, err, "event source \"shipyard-controller\" requires a .triggered event type")
+
+	c.fallback = NewMemoryEventSource(
+		newMockEventInContext("sh.keptn.event.test.started", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		newMockEventInContext("sh.keptn.event.test.finished", "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"),
+		newMockEventInContext("sh.keptn.event.test.finished", "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"),
+	)
+
+	events, err = c.GetEvents(context.Background(), EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 2)
+
+	events, err = c.GetEvents(context.Background(), EventFilter{
+		KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		Type:         "sh.keptn.event.test.finished",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+}
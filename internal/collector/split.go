@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// Maximum number of sub-windows a window is split into
+const MaxSubWindows = 100
+
+// WindowSplit slices a window into sub-windows, either into Count windows of equal duration or into
+// consecutive windows of Duration. The last window of a fixed duration split is shorter if the
+// window isn't a multiple of the duration. A zero split doesn't split the window.
+type WindowSplit struct {
+	Count    int
+	Duration time.Duration
+}
+
+/**
+ * Splits the window into ordered sub-windows. Boundaries of equal splits are truncated to full
+ * seconds. Splits exceeding MaxSubWindows result in an error. Without split, nil is returned.
+ */
+func (s WindowSplit) Apply(window Window) ([]Window, error) {
+	if s.Count <= 0 && s.Duration <= 0 {
+		return nil, nil
+	}
+
+	if !window.End.After(window.Start) {
+		return nil, fmt.Errorf("cannot split window of %s", window.Duration())
+	}
+
+	if s.Duration > 0 {
+		return s.splitByDuration(window)
+	}
+
+	if s.Count > MaxSubWindows {
+		return nil, fmt.Errorf("split into %d windows exceeds maximum of %d", s.Count, MaxSubWindows)
+	}
+
+	subWindows := make([]Window, 0, s.Count)
+	subWindowStart := window.Start
+
+	for i := 1; i <= s.Count; i++ {
+		subWindowEnd := window.End
+		if i < s.Count {
+			subWindowEnd = window.Start.Add(window.Duration() * time.Duration(i) / time.Duration(s.Count)).Truncate(time.Second)
+		}
+
+		if !subWindowEnd.After(subWindowStart) {
+			return nil, fmt.Errorf("window of %s is too short to be split into %d windows", window.Duration(), s.Count)
+		}
+
+		subWindows = append(subWindows, Window{Start: subWindowStart, End: subWindowEnd})
+		subWindowStart = subWindowEnd
+	}
+
+	return subWindows, nil
+}
+
+func (s WindowSplit) splitByDuration(window Window) ([]Window, error) {
+	count := int((window.Duration() + s.Duration - 1) / s.Duration)
+	if count > MaxSubWindows {
+		return nil, fmt.Errorf("split of window of %s into windows of %s exceeds maximum of %d windows", window.Duration(), s.Duration, MaxSubWindows)
+	}
+
+	subWindows := make([]Window, 0, count)
+
+	for subWindowStart := window.Start; subWindowStart.Before(window.End); subWindowStart = subWindowStart.Add(s.Duration) {
+		subWindowEnd := subWindowStart.Add(s.Duration)
+		if subWindowEnd.After(window.End) {
+			subWindowEnd = window.End
+		}
+
+		subWindows = append(subWindows, Window{Start: subWindowStart, End: subWindowEnd})
+	}
+
+	return subWindows, nil
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestWindowSplitApply(t *testing.T) {
+	start := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) time.Time {
+		return start.Add(offset)
+	}
+
+	tests := []struct {
+		name          string
+		split         WindowSplit
+		window        Window
+		expected      []Window
+		expectedError string
+	}{
+		{
+			name:   "unsplit",
+			window: Window{Start: start, End: at(time.Hour)},
+		},
+		{
+			name:   "equal",
+			split:  WindowSplit{Count: 3},
+			window: Window{Start: start, End: at(time.Hour)},
+			expected: []Window{
+				{Start: start, End: at(20 * time.Minute)},
+				{Start: at(20 * time.Minute), End: at(40 * time.Minute)},
+				{Start: at(40 * time.Minute), End: at(time.Hour)},
+			},
+		},
+		{
+			name:   "equal truncated to seconds",
+			split:  WindowSplit{Count: 3},
+			window: Window{Start: start, End: at(10 * time.Second)},
+			expected: []Window{
+				{Start: start, End: at(3 * time.Second)},
+				{Start: at(3 * time.Second), End: at(6 * time.Second)},
+				{Start: at(6 * time.Second), End: at(10 * time.Second)},
+			},
+		},
+		{
+			name:   "fixed duration",
+			split:  WindowSplit{Duration: 25 * time.Minute},
+			window: Window{Start: start, End: at(time.Hour)},
+			expected: []Window{
+				{Start: start, End: at(25 * time.Minute)},
+				{Start: at(25 * time.Minute), End: at(50 * time.Minute)},
+				{Start: at(50 * time.Minute), End: at(time.Hour)},
+			},
+		},
+		{
+			name:          "too short",
+			split:         WindowSplit{Count: 3},
+			window:        Window{Start: start, End: at(2 * time.Second)},
+			expectedError: "window of 2s is too short to be split into 3 windows",
+		},
+		{
+			name:          "too many",
+			split:         WindowSplit{Duration: time.Second},
+			window:        Window{Start: start, End: at(time.Hour)},
+			expectedError: "split of window of 1h0m0s into windows of 1s exceeds maximum of 100 windows",
+		},
+		{
+			name:          "empty",
+			split:         WindowSplit{Count: 2},
+			window:        Window{Start: start, End: start},
+			expectedError: "cannot split window of 0s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subWindows, err := test.split.Apply(test.window)
+			if test.expectedError != "" {
+				assert.Error(t, err, test.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, subWindows, test.expected)
+		})
+	}
+}
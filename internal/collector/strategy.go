@@ -0,0 +1,82 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Strategies picking the event a boundary timestamp is taken from.
+const (
+	StrategyFirst           = "first"
+	StrategyLast            = "last"
+	StrategyNth             = "nth"
+	StrategyAfter           = "after"
+	StrategyBeforeTriggered = "beforeTriggered"
+)
+
+// SelectionStrategy picks a single event. N is the 1-based occurrence of the nth strategy, negative
+// values count from the last occurrence. Reference is the anchor time of the after strategy and the
+// time of the triggering event of the beforeTriggered strategy.
+type SelectionStrategy struct {
+	Name      string
+	N         int
+	Reference time.Time
+}
+
+/**
+ * Picks a single event by the strategy. Events are ordered by time, events sharing a timestamp keep
+ * the order they were fetched in.
+ */
+func SelectEvent(events []*EventEnvelope, strategy SelectionStrategy) (*EventEnvelope, error) {
+	orderedEvents := make([]*EventEnvelope, 0, len(events))
+	for _, event := range events {
+		if !event.Time.IsZero() {
+			orderedEvents = append(orderedEvents, event)
+		}
+	}
+
+	sort.SliceStable(orderedEvents, func(i, j int) bool {
+		return orderedEvents[i].Time.Before(orderedEvents[j].Time)
+	})
+
+	if len(orderedEvents) == 0 {
+		return nil, fmt.Errorf("no timestamps found")
+	}
+
+	switch strategy.Name {
+	case "", StrategyFirst:
+		return orderedEvents[0], nil
+	case StrategyLast:
+		return orderedEvents[len(orderedEvents)-1], nil
+	case StrategyNth:
+		index := strategy.N - 1
+		if strategy.N < 0 {
+			index = len(orderedEvents) + strategy.N
+		}
+
+		if strategy.N == 0 || index < 0 || index >= len(orderedEvents) {
+			return nil, fmt.Errorf("no occurrence %d among %d events", strategy.N, len(orderedEvents))
+		}
+
+		return orderedEvents[index], nil
+	case StrategyAfter:
+		for _, event := range orderedEvents {
+			if event.Time.After(strategy.Reference) {
+				return event, nil
+			}
+		}
+
+		return nil, fmt.Errorf("no event after %s", strategy.Reference.Format(time.RFC3339))
+	case StrategyBeforeTriggered:
+		for i := len(orderedEvents) - 1; i >= 0; i-- {
+			if orderedEvents[i].Time.Before(strategy.Reference) {
+				return orderedEvents[i], nil
+			}
+		}
+
+		return nil, fmt.Errorf("no event before %s", strategy.Reference.Format(time.RFC3339))
+	default:
+		return nil, fmt.Errorf("unknown strategy \"%s\"", strategy.Name)
+	}
+}
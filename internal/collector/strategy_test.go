@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func TestSelectEvent(t *testing.T) {
+	newEvent := func(id string, timestamp time.Time) cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetID(id)
+		event.SetTime(timestamp)
+
+		return event
+	}
+
+	events := mustDecodeEvents(t,
+		newEvent("c", time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)),
+		newEvent("a", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC)),
+		newEvent("b", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC)),
+		cloudevents.NewEvent(),
+	)
+
+	tests := []struct {
+		strategy   SelectionStrategy
+		expectedId string
+	}{
+		{strategy: SelectionStrategy{}, expectedId: "a"},
+		{strategy: SelectionStrategy{Name: StrategyFirst}, expectedId: "a"},
+		{strategy: SelectionStrategy{Name: StrategyLast}, expectedId: "c"},
+		{strategy: SelectionStrategy{Name: StrategyNth, N: 2}, expectedId: "b"},
+		{strategy: SelectionStrategy{Name: StrategyNth, N: -1}, expectedId: "c"},
+		{strategy: SelectionStrategy{Name: StrategyAfter, Reference: time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC)}, expectedId: "b"},
+		{strategy: SelectionStrategy{Name: StrategyBeforeTriggered, Reference: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)}, expectedId: "b"},
+	}
+
+	for _, test := range tests {
+		event, err := SelectEvent(events, test.strategy)
+		assert.NilError(t, err, test.strategy.Name)
+		assert.Equal(t, event.Event.ID(), test.expectedId, test.strategy.Name)
+	}
+
+	_, err := SelectEvent(events, SelectionStrategy{Name: StrategyNth, N: 4})
+	assert.Error(t, err, "no occurrence 4 among 3 events")
+
+	_, err = SelectEvent(events, SelectionStrategy{Name: StrategyAfter, Reference: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)})
+	assert.Error(t, err, "no event after 2022-04-07T12:00:00Z")
+
+	_, err = SelectEvent(mustDecodeEvents(t), SelectionStrategy{Name: StrategyFirst})
+	assert.Error(t, err, "no timestamps found")
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Sources the timestamp of an event is read from.
+const (
+	TimestampCloudEvent = "cloudevent"
+	TimestampField      = "field"
+)
+
+// TimestampSource reads the timestamp of an event from its CloudEvent time or a field of its data,
+// e.g. test.start of a sh.keptn.event.test.finished event.
+type TimestampSource struct {
+	Kind string
+	Path string
+}
+
+/**
+ * Reads the timestamp of the event. Data fields hold either RFC3339 timestamps or Unix timestamps
+ * in seconds. Missing or malformed timestamps result in an error.
+ */
+func (s TimestampSource) Resolve(event *EventEnvelope) (time.Time, error) {
+	switch s.Kind {
+	case TimestampField:
+		return resolveFieldTimestamp(event, s.Path)
+	default:
+		if event.Time.IsZero() {
+			return time.Time{}, fmt.Errorf("event %s has no time", event.Event.ID())
+		}
+
+		return event.Time, nil
+	}
+}
+
+/**
+ * Describes the source for the finished event, e.g. field test.start
+ */
+func (s TimestampSource) String() string {
+	if s.Kind == TimestampField {
+		return fmt.Sprintf("%s %s", s.Kind, s.Path)
+	}
+
+	if s.Kind == "" {
+		return TimestampCloudEvent
+	}
+
+	return s.Kind
+}
+
+func resolveFieldTimestamp(event *EventEnvelope, path string) (time.Time, error) {
+	fields, err := event.Fields()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode data of event %s: %w", event.Event.ID(), err)
+	}
+
+	var value interface{} = fields
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return time.Time{}, fmt.Errorf("field %s not found in event %s", path, event.Event.ID())
+		}
+
+		value, ok = object[name]
+		if !ok || value == nil {
+			return time.Time{}, fmt.Errorf("field %s not found in event %s", path, event.Event.ID())
+		}
+	}
+
+	switch typedValue := value.(type) {
+	case string:
+		timestamp, err := time.Parse(time.RFC3339Nano, typedValue)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("field %s of event %s is no RFC3339 timestamp: \"%s\"", path, event.Event.ID(), typedValue)
+		}
+
+		return timestamp, nil
+	case float64:
+		seconds := int64(typedValue)
+		nanoseconds := int64((typedValue - float64(seconds)) * float64(time.Second))
+
+		return time.Unix(seconds, nanoseconds).UTC(), nil
+	default:
+		return time.Time{}, fmt.Errorf("field %s of event %s is no timestamp", path, event.Event.ID())
+	}
+}
@@ -0,0 +1,56 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func TestTimestampSourceResolve(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("finished")
+	event.SetType("sh.keptn.event.test.finished")
+	event.SetTime(time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC))
+	event.DataEncoded = []byte(`{
+		"test": {"start": "2022-04-07T11:00:00Z", "end": "2022-04-07T11:30:00.5Z", "result": "pass"},
+		"executedAt": 1649329200.25
+	}`)
+
+	withoutTime := cloudevents.NewEvent()
+	withoutTime.SetID("untimed")
+
+	events := mustDecodeEvents(t, event, withoutTime)
+
+	tests := []struct {
+		source        TimestampSource
+		event         *EventEnvelope
+		expected      time.Time
+		expectedError string
+	}{
+		{source: TimestampSource{}, event: events[0], expected: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)},
+		{source: TimestampSource{Kind: TimestampCloudEvent}, event: events[0], expected: time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)},
+		{source: TimestampSource{Kind: TimestampField, Path: "test.start"}, event: events[0], expected: time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC)},
+		{source: TimestampSource{Kind: TimestampField, Path: "test.end"}, event: events[0], expected: time.Date(2022, 4, 7, 11, 30, 0, 500000000, time.UTC)},
+		{source: TimestampSource{Kind: TimestampField, Path: "executedAt"}, event: events[0], expected: time.Date(2022, 4, 7, 11, 0, 0, 250000000, time.UTC)},
+		{source: TimestampSource{Kind: TimestampField, Path: "test.duration"}, event: events[0], expectedError: "field test.duration not found in event finished"},
+		{source: TimestampSource{Kind: TimestampField, Path: "test.start.seconds"}, event: events[0], expectedError: "field test.start.seconds not found in event finished"},
+		{source: TimestampSource{Kind: TimestampField, Path: "test.result"}, event: events[0], expectedError: "field test.result of event finished is no RFC3339 timestamp: \"pass\""},
+		{source: TimestampSource{Kind: TimestampField, Path: "test"}, event: events[0], expectedError: "field test of event finished is no timestamp"},
+		{source: TimestampSource{Kind: TimestampCloudEvent}, event: events[1], expectedError: "event untimed has no time"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source.String(), func(t *testing.T) {
+			timestamp, err := test.source.Resolve(test.event)
+			if test.expectedError != "" {
+				assert.Error(t, err, test.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Assert(t, timestamp.Equal(test.expected), "expected %s, got %s", test.expected, timestamp)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// WindowTrims cut the warm-up and cool-down phases off a window. The warm-up ends either a fixed
+// duration after the start or at the earliest warm-up marker within the window. The cool-down
+// begins either a fixed duration before the end or at the earliest cool-down marker following
+// the warm-up.
+type WindowTrims struct {
+	WarmUp          time.Duration
+	WarmUpMarkers   []*EventEnvelope
+	CoolDown        time.Duration
+	CoolDownMarkers []*EventEnvelope
+
+	// Set if the respective phase ends or begins at a marker
+	HasWarmUpMarker   bool
+	HasCoolDownMarker bool
+}
+
+/**
+ * Trims the window. Every applied trim is described in the returned list. Markers missing from the
+ * window and trims leaving an empty window result in an error. Windows without trims are returned as is.
+ */
+func (t WindowTrims) Apply(window Window) (Window, []string, error) {
+	trimmed := window
+	adjustments := []string{}
+
+	if t.WarmUp > 0 {
+		trimmed.Start = trimmed.Start.Add(t.WarmUp)
+		adjustments = append(adjustments, fmt.Sprintf("trimmed warm-up of %s", t.WarmUp))
+	}
+
+	if t.HasWarmUpMarker {
+		marker := earliestEventWithin(t.WarmUpMarkers, trimmed)
+		if marker == nil {
+			return Window{}, nil, fmt.Errorf("no warm-up marker event found within window")
+		}
+
+		adjustments = append(adjustments, fmt.Sprintf("trimmed warm-up of %s up to marker event %s", marker.Time.Sub(trimmed.Start), marker.Event.ID()))
+		trimmed.Start = marker.Time
+	}
+
+	if t.CoolDown > 0 {
+		trimmed.End = trimmed.End.Add(-t.CoolDown)
+		adjustments = append(adjustments, fmt.Sprintf("trimmed cool-down of %s", t.CoolDown))
+	}
+
+	if t.HasCoolDownMarker {
+		marker := earliestEventWithin(t.CoolDownMarkers, trimmed)
+		if marker == nil {
+			return Window{}, nil, fmt.Errorf("no cool-down marker event found within window")
+		}
+
+		adjustments = append(adjustments, fmt.Sprintf("trimmed cool-down of %s from marker event %s", trimmed.End.Sub(marker.Time), marker.Event.ID()))
+		trimmed.End = marker.Time
+	}
+
+	if len(adjustments) > 0 && !trimmed.End.After(trimmed.Start) {
+		return Window{}, nil, fmt.Errorf("trims leave no time of window of %s", window.Duration())
+	}
+
+	return trimmed, adjustments, nil
+}
+
+/**
+ * Restricts events to the ones labeled with the key and value. An empty value matches any event
+ * carrying the label.
+ */
+func SelectByLabel(events []*EventEnvelope, key string, value string) []*EventEnvelope {
+	selected := []*EventEnvelope{}
+
+	for _, event := range events {
+		labelValue, ok := event.Data.Labels[key]
+		if ok && (value == "" || labelValue == value) {
+			selected = append(selected, event)
+		}
+	}
+
+	return selected
+}
+
+func earliestEventWithin(events []*EventEnvelope, window Window) *EventEnvelope {
+	var earliest *EventEnvelope
+
+	for _, event := range events {
+		if !event.Time.After(window.Start) || !event.Time.Before(window.End) {
+			continue
+		}
+
+		if earliest == nil || event.Time.Before(earliest.Time) {
+			earliest = event
+		}
+	}
+
+	return earliest
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"gotest.tools/assert"
+)
+
+func TestWindowTrimsApply(t *testing.T) {
+	start := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+	window := Window{Start: start, End: start.Add(time.Hour)}
+
+	newMarker := func(id string, phase string, offset time.Duration) cloudevents.Event {
+		event := cloudevents.NewEvent()
+		event.SetID(id)
+		event.SetTime(start.Add(offset))
+		event.DataEncoded = []byte(`{"labels": {"phase": "` + phase + `"}}`)
+
+		return event
+	}
+
+	markers := mustDecodeEvents(t,
+		newMarker("before", "steady", -time.Minute),
+		newMarker("steady-2", "steady", 20*time.Minute),
+		newMarker("steady-1", "steady", 10*time.Minute),
+		newMarker("ramp-down", "rampDown", 50*time.Minute),
+	)
+
+	tests := []struct {
+		name                string
+		trims               WindowTrims
+		expected            Window
+		expectedAdjustments []string
+		expectedError       string
+	}{
+		{
+			name:                "untrimmed",
+			expected:            window,
+			expectedAdjustments: []string{},
+		},
+		{
+			name:                "durations",
+			trims:               WindowTrims{WarmUp: 5 * time.Minute, CoolDown: 10 * time.Minute},
+			expected:            Window{Start: start.Add(5 * time.Minute), End: start.Add(50 * time.Minute)},
+			expectedAdjustments: []string{"trimmed warm-up of 5m0s", "trimmed cool-down of 10m0s"},
+		},
+		{
+			name: "markers",
+			trims: WindowTrims{
+				WarmUpMarkers:     SelectByLabel(markers, "phase", "steady"),
+				HasWarmUpMarker:   true,
+				CoolDownMarkers:   SelectByLabel(markers, "phase", "rampDown"),
+				HasCoolDownMarker: true,
+			},
+			expected: Window{Start: start.Add(10 * time.Minute), End: start.Add(50 * time.Minute)},
+			expectedAdjustments: []string{
+				"trimmed warm-up of 10m0s up to marker event steady-1",
+				"trimmed cool-down of 10m0s from marker event ramp-down",
+			},
+		},
+		{
+			name:          "missing marker",
+			trims:         WindowTrims{CoolDownMarkers: SelectByLabel(markers, "phase", "cooldown"), HasCoolDownMarker: true},
+			expectedError: "no cool-down marker event found within window",
+		},
+		{
+			name:          "exceeding window",
+			trims:         WindowTrims{WarmUp: 30 * time.Minute, CoolDown: 30 * time.Minute},
+			expectedError: "trims leave no time of window of 1h0m0s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trimmed, adjustments, err := test.trims.Apply(window)
+			if test.expectedError != "" {
+				assert.Error(t, err, test.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, trimmed, test.expected)
+			assert.DeepEqual(t, adjustments, test.expectedAdjustments)
+		})
+	}
+}
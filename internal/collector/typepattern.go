@@ -0,0 +1,207 @@
+package collector
+
+import (
+	"container/list"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Upper bound of the number of compiled type patterns kept in memory
+const maxCompiledTypePatterns = 256
+
+// compiledTypePatterns caches the regular expressions of EventFilter.TypePattern, as filters are
+// matched against every fetched event. Patterns come from incoming events, so the least recently
+// used ones are evicted once maxCompiledTypePatterns is exceeded.
+var compiledTypePatterns = newTypePatternCache(maxCompiledTypePatterns)
+
+type typePatternCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type typePatternCacheEntry struct {
+	pattern    string
+	expression *regexp.Regexp
+}
+
+func newTypePatternCache(maxEntries int) *typePatternCache {
+	return &typePatternCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+func (c *typePatternCache) get(pattern string) (*regexp.Regexp, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[pattern]
+	if !ok {
+		return nil, false
+	}
+
+	c.lru.MoveToFront(element)
+
+	return element.Value.(*typePatternCacheEntry).expression, true
+}
+
+func (c *typePatternCache) put(pattern string, expression *regexp.Regexp) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[pattern]; ok {
+		c.lru.MoveToFront(element)
+		return
+	}
+
+	c.entries[pattern] = c.lru.PushFront(&typePatternCacheEntry{pattern: pattern, expression: expression})
+
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*typePatternCacheEntry).pattern)
+	}
+}
+
+func (c *typePatternCache) len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lru.Len()
+}
+
+/**
+ * Sets the event types the filter matches. A single literal type is matched exactly and can be
+ * pushed down to the event source. Lists and patterns are combined into one regular expression
+ * that is applied client-side. Every type is either a literal, a glob such as
+ * sh.keptn.event.*.started or sh.keptn.event.(test|load-test).finished, or a regular expression
+ * enclosed in slashes.
+ */
+func (f *EventFilter) SetTypes(types ...string) error {
+	f.Type = ""
+	f.TypePattern = ""
+
+	nonEmptyTypes := []string{}
+	for _, eventType := range types {
+		if eventType != "" {
+			nonEmptyTypes = append(nonEmptyTypes, eventType)
+		}
+	}
+
+	if len(nonEmptyTypes) == 0 {
+		return nil
+	}
+
+	if len(nonEmptyTypes) == 1 && !isTypePattern(nonEmptyTypes[0]) {
+		f.Type = nonEmptyTypes[0]
+		return nil
+	}
+
+	expressions := []string{}
+	for _, eventType := range nonEmptyTypes {
+		expression, err := typeToExpression(eventType)
+		if err != nil {
+			return err
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	pattern := "^(?:" + strings.Join(expressions, "|") + ")$"
+	_, err := compileTypePattern(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid event type pattern \"%s\": %w", strings.Join(nonEmptyTypes, ", "), err)
+	}
+
+	f.TypePattern = pattern
+
+	return nil
+}
+
+func isTypePattern(eventType string) bool {
+	return isRegularExpression(eventType) || strings.ContainsAny(eventType, "*?[(|")
+}
+
+func isRegularExpression(eventType string) bool {
+	return len(eventType) > 1 && strings.HasPrefix(eventType, "/") && strings.HasSuffix(eventType, "/")
+}
+
+/**
+ * Translates an event type into a regular expression. In globs, * matches any sequence of
+ * characters, ? a single character, [...] a character class and (a|b) one of the alternatives.
+ */
+func typeToExpression(eventType string) (string, error) {
+	if isRegularExpression(eventType) {
+		expression := eventType[1 : len(eventType)-1]
+		_, err := regexp.Compile(expression)
+		if err != nil {
+			return "", fmt.Errorf("invalid event type pattern \"%s\": %w", eventType, err)
+		}
+
+		return "(?:" + expression + ")", nil
+	}
+
+	expression := strings.Builder{}
+	isInClass := false
+
+	for _, char := range eventType {
+		switch {
+		case isInClass:
+			expression.WriteRune(char)
+			isInClass = char != ']'
+		case char == '*':
+			expression.WriteString(".*")
+		case char == '?':
+			expression.WriteString(".")
+		case char == '[':
+			expression.WriteRune(char)
+			isInClass = true
+		case char == '(':
+			expression.WriteString("(?:")
+		case char == ')' || char == '|':
+			expression.WriteRune(char)
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+
+	return expression.String(), nil
+}
+
+func compileTypePattern(pattern string) (*regexp.Regexp, error) {
+	compiled, ok := compiledTypePatterns.get(pattern)
+	if ok {
+		return compiled, nil
+	}
+
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	compiledTypePatterns.put(pattern, expression)
+
+	return expression, nil
+}
+
+func matchesType(filter EventFilter, eventType string) bool {
+	if filter.Type != "" && filter.Type != eventType {
+		return false
+	}
+
+	if filter.TypePattern == "" {
+		return true
+	}
+
+	expression, err := compileTypePattern(filter.TypePattern)
+	if err != nil {
+		return false
+	}
+
+	return expression.MatchString(eventType)
+}
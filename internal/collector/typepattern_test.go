@@ -0,0 +1,110 @@
+package collector
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestSetTypes(t *testing.T) {
+	filter := EventFilter{}
+
+	err := filter.SetTypes("sh.keptn.event.test.finished")
+	assert.NilError(t, err)
+	assert.Equal(t, filter.Type, "sh.keptn.event.test.finished")
+	assert.Equal(t, filter.TypePattern, "")
+
+	err = filter.SetTypes()
+	assert.NilError(t, err)
+	assert.Equal(t, filter, EventFilter{})
+
+	tests := []struct {
+		types      []string
+		matching   []string
+		mismatched []string
+	}{
+		{
+			types:      []string{"sh.keptn.event.test.started", "sh.keptn.event.load-test.started"},
+			matching:   []string{"sh.keptn.event.test.started", "sh.keptn.event.load-test.started"},
+			mismatched: []string{"sh.keptn.event.test.finished", "sh.keptn.event.testXstarted"},
+		},
+		{
+			types:      []string{"sh.keptn.event.*.started"},
+			matching:   []string{"sh.keptn.event.test.started", "sh.keptn.event.load-test.started"},
+			mismatched: []string{"sh.keptn.event.test.finished", "sh.keptn.event.test.started.old"},
+		},
+		{
+			types:      []string{"sh.keptn.event.(test|load-test).finished"},
+			matching:   []string{"sh.keptn.event.test.finished", "sh.keptn.event.load-test.finished"},
+			mismatched: []string{"sh.keptn.event.get-sli.finished", "sh.keptn.event.test.started"},
+		},
+		{
+			types:      []string{"/sh\\.keptn\\.event\\.[a-z-]+\\.finished/"},
+			matching:   []string{"sh.keptn.event.test.finished"},
+			mismatched: []string{"sh.keptn.event.test.started"},
+		},
+	}
+
+	for _, test := range tests {
+		err := filter.SetTypes(test.types...)
+		assert.NilError(t, err)
+		assert.Equal(t, filter.Type, "")
+
+		for _, eventType := range test.matching {
+			assert.Assert(t, matchesType(filter, eventType), "%v should match %s", test.types, eventType)
+		}
+
+		for _, eventType := range test.mismatched {
+			assert.Assert(t, !matchesType(filter, eventType), "%v should not match %s", test.types, eventType)
+		}
+	}
+
+	err = filter.SetTypes("/sh.keptn.event.(test/")
+	assert.ErrorContains(t, err, "invalid event type pattern \"/sh.keptn.event.(test/\"")
+}
+
+func TestGetFilteredEventsWithTypePattern(t *testing.T) {
+	testStartedEvent := newMockTestStartedEvent()
+	testStartedEvent.SetExtension("shkeptncontext", "keptnContext")
+
+	testFinishedEvent := newMockTestFinishedEvent()
+	testFinishedEvent.SetExtension("shkeptncontext", "keptnContext")
+
+	eventSource := &typeOnlyEventSource{
+		MemoryEventSource: NewMemoryEventSource(testStartedEvent, testFinishedEvent),
+	}
+	c := NewCollector(eventSource)
+
+	filter := EventFilter{KeptnContext: "keptnContext"}
+	err := filter.SetTypes("sh.keptn.event.*.started")
+	assert.NilError(t, err)
+
+	events, _, err := c.GetFilteredEvents(context.Background(), filter)
+	assert.NilError(t, err)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, events[0].Event.Type(), "sh.keptn.event.test.started")
+	assert.DeepEqual(t, eventSource.receivedFilters, []EventFilter{{KeptnContext: "keptnContext"}})
+}
+
+func TestTypePatternCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newTypePatternCache(2)
+
+	for _, pattern := range []string{"^a$", "^b$", "^a$", "^c$"} {
+		_, ok := cache.get(pattern)
+		if !ok {
+			cache.put(pattern, regexp.MustCompile(pattern))
+		}
+	}
+	assert.Equal(t, cache.len(), 2)
+
+	_, ok := cache.get("^a$")
+	assert.Assert(t, ok)
+
+	_, ok = cache.get("^b$")
+	assert.Assert(t, !ok)
+
+	_, ok = cache.get("^c$")
+	assert.Assert(t, ok)
+}
@@ -0,0 +1,170 @@
+package collector
+
+import (
+	"fmt"
+	"time"
+)
+
+// Directions a window is grown in to reach its minimum duration.
+const (
+	GrowSymmetric = "symmetric"
+	GrowForward   = "forward"
+)
+
+// Policies applied to windows exceeding their maximum duration.
+const (
+	MaxPolicyFail      = "fail"
+	MaxPolicyTrimStart = "trimStart"
+	MaxPolicyTrimEnd   = "trimEnd"
+)
+
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+func (w Window) Duration() time.Duration {
+	return w.End.Sub(w.Start)
+}
+
+/**
+ * Formats the duration of the window as Keptn timeframe, e.g. 1h30m or 45s. Zero units and
+ * fractions of a second are omitted. Inverted windows have no timeframe.
+ */
+func (w Window) Timeframe() string {
+	duration := w.Duration().Truncate(time.Second)
+	if duration < 0 {
+		return ""
+	}
+
+	if duration == 0 {
+		return "0s"
+	}
+
+	timeframe := ""
+
+	hours := duration / time.Hour
+	if hours > 0 {
+		timeframe += fmt.Sprintf("%dh", hours)
+	}
+
+	minutes := duration % time.Hour / time.Minute
+	if minutes > 0 {
+		timeframe += fmt.Sprintf("%dm", minutes)
+	}
+
+	seconds := duration % time.Minute / time.Second
+	if seconds > 0 {
+		timeframe += fmt.Sprintf("%ds", seconds)
+	}
+
+	return timeframe
+}
+
+// WindowConstraints pad a window and bound its duration. Zero durations disable the respective constraint.
+type WindowConstraints struct {
+	PrePadding  time.Duration
+	PostPadding time.Duration
+	MinDuration time.Duration
+	MinGrowth   string
+	MaxDuration time.Duration
+	MaxPolicy   string
+}
+
+/**
+ * Pads the window, grows it to the minimum and trims it to the maximum duration. Every applied
+ * adjustment is described in the returned list. Windows exceeding the maximum duration result in an
+ * error if the policy is fail, inverted windows result in an error if they would have to be grown.
+ */
+func (c WindowConstraints) Apply(window Window) (Window, []string, error) {
+	adjustments := []string{}
+
+	if c.PrePadding > 0 {
+		window.Start = window.Start.Add(-c.PrePadding)
+		adjustments = append(adjustments, fmt.Sprintf("padded start by %s", c.PrePadding))
+	}
+
+	if c.PostPadding > 0 {
+		window.End = window.End.Add(c.PostPadding)
+		adjustments = append(adjustments, fmt.Sprintf("padded end by %s", c.PostPadding))
+	}
+
+	if c.MinDuration > 0 && window.Duration() < c.MinDuration {
+		if window.Duration() < 0 {
+			return window, adjustments, fmt.Errorf("window end %s is before start %s, can't grow it to minimum of %s", window.End.Format(time.RFC3339), window.Start.Format(time.RFC3339), c.MinDuration)
+		}
+
+		missing := c.MinDuration - window.Duration()
+
+		switch c.MinGrowth {
+		case GrowForward:
+			window.End = window.End.Add(missing)
+		default:
+			window.Start = window.Start.Add(-missing / 2)
+			window.End = window.End.Add(missing - missing/2)
+		}
+
+		adjustments = append(adjustments, fmt.Sprintf("grew window by %s to minimum of %s", missing, c.MinDuration))
+	}
+
+	if c.MaxDuration > 0 && window.Duration() > c.MaxDuration {
+		excess := window.Duration() - c.MaxDuration
+
+		switch c.MaxPolicy {
+		case MaxPolicyTrimStart:
+			window.Start = window.End.Add(-c.MaxDuration)
+			adjustments = append(adjustments, fmt.Sprintf("trimmed start by %s to maximum of %s", excess, c.MaxDuration))
+		case MaxPolicyTrimEnd:
+			window.End = window.Start.Add(c.MaxDuration)
+			adjustments = append(adjustments, fmt.Sprintf("trimmed end by %s to maximum of %s", excess, c.MaxDuration))
+		default:
+			return window, adjustments, fmt.Errorf("window of %s exceeds maximum of %s", window.Duration(), c.MaxDuration)
+		}
+	}
+
+	return window, adjustments, nil
+}
+
+// Policies applied to windows failing validation.
+const (
+	ValidationFail = "fail"
+	ValidationWarn = "warn"
+)
+
+// WindowValidation checks windows for sanity. Timestamps may lie up to FutureSkew in the future to
+// tolerate clock skew. Windows starting more than MaxAge ago are considered stale, a zero MaxAge
+// disables the check.
+type WindowValidation struct {
+	FutureSkew time.Duration
+	MaxAge     time.Duration
+	Policy     string
+}
+
+/**
+ * Checks the rounded window for sanity and describes every problem found. It has to be checked
+ * before it is trimmed or constrained, which could turn an inverted window into a valid one. Event
+ * timestamps are checked for lying in the future, so rounding the window doesn't trigger the check.
+ */
+func (v WindowValidation) Validate(eventWindow Window, window Window, now time.Time) []string {
+	problems := []string{}
+
+	if window.End.Before(window.Start) {
+		problems = append(problems, fmt.Sprintf("window end %s is before start %s", window.End.Format(time.RFC3339), window.Start.Format(time.RFC3339)))
+	} else if window.End.Equal(window.Start) {
+		problems = append(problems, fmt.Sprintf("window is empty, start and end are %s", window.Start.Format(time.RFC3339)))
+	}
+
+	latestAllowed := now.Add(v.FutureSkew)
+	for _, timestamp := range []time.Time{eventWindow.Start, eventWindow.End} {
+		if timestamp.After(latestAllowed) {
+			problems = append(problems, fmt.Sprintf("event time %s is %s in the future, exceeding the tolerated skew of %s", timestamp.Format(time.RFC3339), timestamp.Sub(now).Round(time.Second), v.FutureSkew))
+			break
+		}
+	}
+
+	if v.MaxAge > 0 && now.Sub(window.Start) > v.MaxAge {
+		problems = append(problems, fmt.Sprintf("window start %s is older than the maximum age of %s", window.Start.Format(time.RFC3339), v.MaxAge))
+	}
+
+	return problems
+}
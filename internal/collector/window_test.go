@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestWindowConstraintsApply(t *testing.T) {
+	start := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name                string
+		constraints         WindowConstraints
+		window              Window
+		expected            Window
+		expectedAdjustments []string
+	}{
+		{
+			name:                "unconstrained",
+			window:              Window{Start: start, End: start.Add(10 * time.Second)},
+			expected:            Window{Start: start, End: start.Add(10 * time.Second)},
+			expectedAdjustments: []string{},
+		},
+		{
+			name:                "padding",
+			constraints:         WindowConstraints{PrePadding: time.Minute, PostPadding: 2 * time.Minute},
+			window:              Window{Start: start, End: start.Add(10 * time.Minute)},
+			expected:            Window{Start: start.Add(-time.Minute), End: start.Add(12 * time.Minute)},
+			expectedAdjustments: []string{"padded start by 1m0s", "padded end by 2m0s"},
+		},
+		{
+			name:                "symmetric growth",
+			constraints:         WindowConstraints{MinDuration: time.Minute, MinGrowth: GrowSymmetric},
+			window:              Window{Start: start, End: start.Add(20 * time.Second)},
+			expected:            Window{Start: start.Add(-20 * time.Second), End: start.Add(40 * time.Second)},
+			expectedAdjustments: []string{"grew window by 40s to minimum of 1m0s"},
+		},
+		{
+			name:                "forward growth",
+			constraints:         WindowConstraints{MinDuration: time.Minute, MinGrowth: GrowForward},
+			window:              Window{Start: start, End: start.Add(20 * time.Second)},
+			expected:            Window{Start: start, End: start.Add(time.Minute)},
+			expectedAdjustments: []string{"grew window by 40s to minimum of 1m0s"},
+		},
+		{
+			name:                "trim start",
+			constraints:         WindowConstraints{MaxDuration: time.Hour, MaxPolicy: MaxPolicyTrimStart},
+			window:              Window{Start: start, End: start.Add(3 * time.Hour)},
+			expected:            Window{Start: start.Add(2 * time.Hour), End: start.Add(3 * time.Hour)},
+			expectedAdjustments: []string{"trimmed start by 2h0m0s to maximum of 1h0m0s"},
+		},
+		{
+			name:                "trim end",
+			constraints:         WindowConstraints{PrePadding: time.Minute, MaxDuration: time.Hour, MaxPolicy: MaxPolicyTrimEnd},
+			window:              Window{Start: start, End: start.Add(3 * time.Hour)},
+			expected:            Window{Start: start.Add(-time.Minute), End: start.Add(59 * time.Minute)},
+			expectedAdjustments: []string{"padded start by 1m0s", "trimmed end by 2h1m0s to maximum of 1h0m0s"},
+		},
+	}
+
+	for _, test := range tests {
+		window, adjustments, err := test.constraints.Apply(test.window)
+		assert.NilError(t, err, test.name)
+		assert.Equal(t, window, test.expected, test.name)
+		assert.DeepEqual(t, adjustments, test.expectedAdjustments)
+	}
+
+	_, _, err := WindowConstraints{MaxDuration: time.Hour, MaxPolicy: MaxPolicyFail}.Apply(Window{Start: start, End: start.Add(49 * time.Hour)})
+	assert.Error(t, err, "window of 49h0m0s exceeds maximum of 1h0m0s")
+
+	_, _, err = WindowConstraints{MinDuration: 30 * time.Minute}.Apply(Window{Start: start.Add(10 * time.Minute), End: start})
+	assert.Error(t, err, "window end 2022-04-07T12:00:00Z is before start 2022-04-07T12:10:00Z, can't grow it to minimum of 30m0s")
+}
+
+func TestWindowValidationValidate(t *testing.T) {
+	now := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+	validation := WindowValidation{FutureSkew: time.Minute, MaxAge: 24 * time.Hour}
+
+	tests := []struct {
+		name             string
+		eventWindow      Window
+		window           Window
+		expectedProblems []string
+	}{
+		{
+			name:             "valid",
+			eventWindow:      Window{Start: now.Add(-time.Hour), End: now.Add(30 * time.Second)},
+			window:           Window{Start: now.Add(-time.Hour), End: now.Add(time.Minute)},
+			expectedProblems: []string{},
+		},
+		{
+			name:             "inverted",
+			eventWindow:      Window{Start: now.Add(-time.Hour), End: now.Add(-2 * time.Hour)},
+			window:           Window{Start: now.Add(-time.Hour), End: now.Add(-2 * time.Hour)},
+			expectedProblems: []string{"window end 2022-04-07T10:00:00Z is before start 2022-04-07T11:00:00Z"},
+		},
+		{
+			name:             "empty",
+			eventWindow:      Window{Start: now.Add(-time.Hour), End: now.Add(-time.Hour)},
+			window:           Window{Start: now.Add(-time.Hour), End: now.Add(-time.Hour)},
+			expectedProblems: []string{"window is empty, start and end are 2022-04-07T11:00:00Z"},
+		},
+		{
+			name:             "future",
+			eventWindow:      Window{Start: now.Add(-time.Hour), End: now.Add(5 * time.Minute)},
+			window:           Window{Start: now.Add(-time.Hour), End: now.Add(5 * time.Minute)},
+			expectedProblems: []string{"event time 2022-04-07T12:05:00Z is 5m0s in the future, exceeding the tolerated skew of 1m0s"},
+		},
+		{
+			name:             "stale",
+			eventWindow:      Window{Start: now.Add(-48 * time.Hour), End: now.Add(-47 * time.Hour)},
+			window:           Window{Start: now.Add(-48 * time.Hour), End: now.Add(-47 * time.Hour)},
+			expectedProblems: []string{"window start 2022-04-05T12:00:00Z is older than the maximum age of 24h0m0s"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.DeepEqual(t, validation.Validate(test.eventWindow, test.window, now), test.expectedProblems)
+		})
+	}
+}
+
+func TestWindowTimeframe(t *testing.T) {
+	start := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 0, expected: "0s"},
+		{duration: 45*time.Second + 500*time.Millisecond, expected: "45s"},
+		{duration: 5 * time.Minute, expected: "5m"},
+		{duration: 90 * time.Minute, expected: "1h30m"},
+		{duration: 48*time.Hour + 5*time.Second, expected: "48h5s"},
+		{duration: -time.Minute, expected: ""},
+	}
+
+	for _, test := range tests {
+		window := Window{Start: start, End: start.Add(test.duration)}
+		assert.Equal(t, window.Timeframe(), test.expected, test.duration.String())
+	}
+}
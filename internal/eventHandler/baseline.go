@@ -0,0 +1,58 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+/**
+ * Looks up the baseline among the finished events of previous runs matching any of the filters. A
+ * failed lookup, e.g. because the event source holds too many events, doesn't fail the collection
+ * but is reported in the returned warnings like a missing baseline.
+ */
+func lookUpBaseline(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	filters []collector.EventFilter,
+	currentContext string,
+) (*BaselineData, []string) {
+	warnings := []string{}
+
+	eventsByFilter, decodeWarnings, err := fetchFilteredEvents(ctx, collectorIface, filters)
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to look up baseline: %s", err.Error()))
+		return nil, warnings
+	}
+
+	for _, decodeWarning := range decodeWarnings {
+		warnings = append(warnings, decodeWarning.String())
+	}
+
+	events := []*collector.EventEnvelope{}
+	for _, filter := range filters {
+		events = append(events, eventsByFilter[filter]...)
+	}
+
+	baseline, baselineWarnings := collector.FindBaseline(events, currentContext)
+	warnings = append(warnings, baselineWarnings...)
+
+	if baseline == nil {
+		descriptions := []string{}
+		for _, filter := range filters {
+			descriptions = append(descriptions, filter.String())
+		}
+
+		warnings = append(warnings, fmt.Sprintf("no baseline found for %s", strings.Join(descriptions, " or ")))
+		return nil, warnings
+	}
+
+	return &BaselineData{
+		Context:    baseline.Event.KeptnContext,
+		EventId:    baseline.Event.Event.ID(),
+		EventType:  baseline.Event.Event.Type(),
+		Evaluation: newEvaluationData(baseline.Window),
+	}, warnings
+}
@@ -0,0 +1,72 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+	"gotest.tools/assert"
+)
+
+func TestLookUpBaseline(t *testing.T) {
+	collectionFilter := collector.EventFilter{Project: "simplenode-gitlab", Stage: "staging", Service: "simplenodeservice", Type: collector.CollectionFinishedEventType}
+	evaluationFilter := collector.EventFilter{Project: "simplenode-gitlab", Stage: "staging", Service: "simplenodeservice", Type: collector.EvaluationFinishedEventType}
+
+	passed, err := collector.NewEventEnvelope(newTestEvent("previous-passed", "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01", collector.EvaluationFinishedEventType, time.Date(2022, 4, 6, 11, 0, 0, 0, time.UTC),
+		`{"status": "succeeded", "result": "pass", "evaluation": {"timeStart": "2022-04-06T10:00:00Z", "timeEnd": "2022-04-06T10:30:00Z"}}`))
+	assert.NilError(t, err)
+
+	tests := []struct {
+		name             string
+		evaluationEvents []*collector.EventEnvelope
+		evaluationError  error
+		expectedBaseline *BaselineData
+		expectedWarnings []string
+	}{
+		{
+			name:             "found",
+			evaluationEvents: []*collector.EventEnvelope{passed},
+			expectedBaseline: &BaselineData{
+				Context:    "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01",
+				EventId:    "previous-passed",
+				EventType:  collector.EvaluationFinishedEventType,
+				Evaluation: EvaluationData{Start: "2022-04-06T10:00:00Z", End: "2022-04-06T10:30:00Z", Timeframe: "30m"},
+			},
+			expectedWarnings: []string{},
+		},
+		{
+			name:             "not found",
+			evaluationEvents: []*collector.EventEnvelope{},
+			expectedWarnings: []string{
+				"no baseline found for project \"simplenode-gitlab\", stage \"staging\", service \"simplenodeservice\", type \"sh.keptn.event.collection.finished\" or project \"simplenode-gitlab\", stage \"staging\", service \"simplenodeservice\", type \"sh.keptn.event.evaluation.finished\"",
+			},
+		},
+		{
+			// Failed lookups don't fail the collection
+			name:            "too many events",
+			evaluationError: fmt.Errorf("%w: project \"simplenode-gitlab\" exceeds the maximum of 10000 events", collector.ErrTooManyEvents),
+			expectedWarnings: []string{
+				"failed to look up baseline: failed to fetch events of project \"simplenode-gitlab\", stage \"staging\", service \"simplenodeservice\", type \"sh.keptn.event.evaluation.finished\": too many events: project \"simplenode-gitlab\" exceeds the maximum of 10000 events",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := NewMockCollectorIface(ctrl)
+
+			m.EXPECT().GetFilteredEvents(gomock.Any(), collectionFilter).Return([]*collector.EventEnvelope{}, []collector.DecodeWarning{}, nil).MaxTimes(1)
+			m.EXPECT().GetFilteredEvents(gomock.Any(), evaluationFilter).Return(test.evaluationEvents, []collector.DecodeWarning{}, test.evaluationError).Times(1)
+
+			baseline, warnings := lookUpBaseline(context.Background(), m, []collector.EventFilter{collectionFilter, evaluationFilter}, testKeptnContext)
+			assert.DeepEqual(t, baseline, test.expectedBaseline)
+			assert.DeepEqual(t, warnings, test.expectedWarnings)
+		})
+	}
+}
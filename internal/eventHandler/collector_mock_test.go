@@ -5,11 +5,13 @@
 package eventHandler
 
 import (
+	context "context"
 	reflect "reflect"
 	time "time"
 
 	v2 "github.com/cloudevents/sdk-go/v2"
 	gomock "github.com/golang/mock/gomock"
+	collector "github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
 )
 
 // MockCollectorIface is a mock of CollectorIface interface.
@@ -36,12 +38,11 @@ func (m *MockCollectorIface) EXPECT() *MockCollectorIfaceMockRecorder {
 }
 
 // CollectBatchIds mocks base method.
-func (m *MockCollectorIface) CollectBatchIds(events []v2.Event) ([]string, error) {
+func (m *MockCollectorIface) CollectBatchIds(events []*collector.EventEnvelope) []string {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CollectBatchIds", events)
 	ret0, _ := ret[0].([]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	return ret0
 }
 
 // CollectBatchIds indicates an expected call of CollectBatchIds.
@@ -51,7 +52,7 @@ func (mr *MockCollectorIfaceMockRecorder) CollectBatchIds(events interface{}) *g
 }
 
 // CollectEarliestTime mocks base method.
-func (m *MockCollectorIface) CollectEarliestTime(events []v2.Event, isFloored bool) (time.Time, error) {
+func (m *MockCollectorIface) CollectEarliestTime(events []*collector.EventEnvelope, isFloored bool) (time.Time, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CollectEarliestTime", events, isFloored)
 	ret0, _ := ret[0].(time.Time)
@@ -66,12 +67,11 @@ func (mr *MockCollectorIfaceMockRecorder) CollectEarliestTime(events, isFloored
 }
 
 // CollectExecutionIds mocks base method.
-func (m *MockCollectorIface) CollectExecutionIds(events []v2.Event) ([]string, error) {
+func (m *MockCollectorIface) CollectExecutionIds(events []*collector.EventEnvelope) []string {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CollectExecutionIds", events)
 	ret0, _ := ret[0].([]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	return ret0
 }
 
 // CollectExecutionIds indicates an expected call of CollectExecutionIds.
@@ -81,7 +81,7 @@ func (mr *MockCollectorIfaceMockRecorder) CollectExecutionIds(events interface{}
 }
 
 // CollectLatestTime mocks base method.
-func (m *MockCollectorIface) CollectLatestTime(events []v2.Event, isCeiled bool) (time.Time, error) {
+func (m *MockCollectorIface) CollectLatestTime(events []*collector.EventEnvelope, isCeiled bool) (time.Time, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CollectLatestTime", events, isCeiled)
 	ret0, _ := ret[0].(time.Time)
@@ -96,40 +96,56 @@ func (mr *MockCollectorIfaceMockRecorder) CollectLatestTime(events, isCeiled int
 }
 
 // GetEvents mocks base method.
-func (m *MockCollectorIface) GetEvents(keptnContext string) ([]v2.Event, error) {
+func (m *MockCollectorIface) GetEvents(ctx context.Context, keptnContext string) ([]v2.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEvents", keptnContext)
+	ret := m.ctrl.Call(m, "GetEvents", ctx, keptnContext)
 	ret0, _ := ret[0].([]v2.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEvents indicates an expected call of GetEvents.
-func (mr *MockCollectorIfaceMockRecorder) GetEvents(keptnContext interface{}) *gomock.Call {
+func (mr *MockCollectorIfaceMockRecorder) GetEvents(ctx, keptnContext interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEvents", reflect.TypeOf((*MockCollectorIface)(nil).GetEvents), keptnContext)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEvents", reflect.TypeOf((*MockCollectorIface)(nil).GetEvents), ctx, keptnContext)
 }
 
 // GetEventsOfType mocks base method.
-func (m *MockCollectorIface) GetEventsOfType(eventType, keptnContext string) ([]v2.Event, error) {
+func (m *MockCollectorIface) GetEventsOfType(ctx context.Context, eventType, keptnContext string) ([]v2.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEventsOfType", eventType, keptnContext)
+	ret := m.ctrl.Call(m, "GetEventsOfType", ctx, eventType, keptnContext)
 	ret0, _ := ret[0].([]v2.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetEventsOfType indicates an expected call of GetEventsOfType.
-func (mr *MockCollectorIfaceMockRecorder) GetEventsOfType(eventType, keptnContext interface{}) *gomock.Call {
+func (mr *MockCollectorIfaceMockRecorder) GetEventsOfType(ctx, eventType, keptnContext interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventsOfType", reflect.TypeOf((*MockCollectorIface)(nil).GetEventsOfType), eventType, keptnContext)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEventsOfType", reflect.TypeOf((*MockCollectorIface)(nil).GetEventsOfType), ctx, eventType, keptnContext)
+}
+
+// GetFilteredEvents mocks base method.
+func (m *MockCollectorIface) GetFilteredEvents(ctx context.Context, filter collector.EventFilter) ([]*collector.EventEnvelope, []collector.DecodeWarning, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilteredEvents", ctx, filter)
+	ret0, _ := ret[0].([]*collector.EventEnvelope)
+	ret1, _ := ret[1].([]collector.DecodeWarning)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetFilteredEvents indicates an expected call of GetFilteredEvents.
+func (mr *MockCollectorIfaceMockRecorder) GetFilteredEvents(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilteredEvents", reflect.TypeOf((*MockCollectorIface)(nil).GetFilteredEvents), ctx, filter)
 }
 
 // MustParseEvents mocks base method.
-func (m *MockCollectorIface) MustParseEvents(events []v2.Event, typeFilter, stageFilter string) ([]v2.Event, error) {
+func (m *MockCollectorIface) MustParseEvents(events []*collector.EventEnvelope, typeFilter, stageFilter string) ([]*collector.EventEnvelope, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "MustParseEvents", events, typeFilter, stageFilter)
-	ret0, _ := ret[0].([]v2.Event)
+	ret0, _ := ret[0].([]*collector.EventEnvelope)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -141,10 +157,10 @@ func (mr *MockCollectorIfaceMockRecorder) MustParseEvents(events, typeFilter, st
 }
 
 // ParseEvents mocks base method.
-func (m *MockCollectorIface) ParseEvents(events []v2.Event, typeFilter, stageFilter string) []v2.Event {
+func (m *MockCollectorIface) ParseEvents(events []*collector.EventEnvelope, typeFilter, stageFilter string) []*collector.EventEnvelope {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ParseEvents", events, typeFilter, stageFilter)
-	ret0, _ := ret[0].([]v2.Event)
+	ret0, _ := ret[0].([]*collector.EventEnvelope)
 	return ret0
 }
 
@@ -153,3 +169,33 @@ func (mr *MockCollectorIfaceMockRecorder) ParseEvents(events, typeFilter, stageF
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseEvents", reflect.TypeOf((*MockCollectorIface)(nil).ParseEvents), events, typeFilter, stageFilter)
 }
+
+// SelectEvent mocks base method.
+func (m *MockCollectorIface) SelectEvent(events []*collector.EventEnvelope, strategy collector.SelectionStrategy) (*collector.EventEnvelope, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectEvent", events, strategy)
+	ret0, _ := ret[0].(*collector.EventEnvelope)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectEvent indicates an expected call of SelectEvent.
+func (mr *MockCollectorIfaceMockRecorder) SelectEvent(events, strategy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectEvent", reflect.TypeOf((*MockCollectorIface)(nil).SelectEvent), events, strategy)
+}
+
+// SelectExecution mocks base method.
+func (m *MockCollectorIface) SelectExecution(events []*collector.EventEnvelope, selector string) ([]*collector.EventEnvelope, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SelectExecution", events, selector)
+	ret0, _ := ret[0].([]*collector.EventEnvelope)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SelectExecution indicates an expected call of SelectExecution.
+func (mr *MockCollectorIfaceMockRecorder) SelectExecution(events, selector interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SelectExecution", reflect.TypeOf((*MockCollectorIface)(nil).SelectExecution), events, selector)
+}
@@ -0,0 +1,223 @@
+package eventHandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+)
+
+// How long before the triggered sequence the event triggering it is looked up by default
+const defaultTriggeringEventLookback = time.Hour
+
+// How far back latest and label references are looked up by default
+const defaultContextReferenceMaxAge = 30 * 24 * time.Hour
+
+// References to labels of the incoming event within label values, e.g. $LABEL.buildId
+var labelReferencePattern = regexp.MustCompile(`\$LABEL\.([A-Za-z0-9_.-]+)`)
+
+/**
+ * Resolves symbolic context references of the collection through the event source. The age of
+ * referenced contexts is measured from the lookup time.
+ */
+func resolveContextReferences(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	incomingEvent cloudevents.Event,
+	collectionEventDataIface CollectionEventDataIface,
+	lookupTime time.Time,
+) error {
+	eventData := collectionEventDataIface.GetEventData()
+
+	return collectionEventDataIface.ResolveContextReferences(func(reference ContextReference) (string, error) {
+		return resolveContextReference(ctx, collectorIface, incomingEvent, eventData, reference, lookupTime)
+	})
+}
+
+/**
+ * Resolves a context reference to the context of the latest matching event of the project and
+ * service of the incoming event sent within the maximum age, or to the context that triggered the
+ * current one. The current context itself is never referenced.
+ */
+func resolveContextReference(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	incomingEvent cloudevents.Event,
+	eventData keptnv2.EventData,
+	reference ContextReference,
+	lookupTime time.Time,
+) (string, error) {
+	currentContext, ok := incomingEvent.Extensions()["shkeptncontext"].(string)
+	if !ok {
+		return "", fmt.Errorf("error parsing Keptn context")
+	}
+
+	maxAge, err := parseWindowDuration("context reference maximum age", reference.MaxAge)
+	if err != nil {
+		return "", err
+	}
+
+	if reference.Ref == ContextRefTriggering {
+		if maxAge == 0 {
+			maxAge = defaultTriggeringEventLookback
+		}
+
+		return resolveTriggeringContext(ctx, collectorIface, currentContext, eventData, maxAge)
+	}
+
+	filter := collector.EventFilter{
+		Project: eventData.Project,
+		Stage:   reference.Stage,
+		Service: eventData.Service,
+	}
+
+	err = filter.SetTypes(reference.EventType...)
+	if err != nil {
+		return "", err
+	}
+
+	if maxAge == 0 {
+		maxAge = defaultContextReferenceMaxAge
+	}
+
+	filter.FromTime = lookupTime.Add(-maxAge)
+
+	labelValue := ""
+	if reference.Ref == ContextRefLabel {
+		labelValue, err = expandLabelReferences(reference.Value, eventData.GetLabels())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	events, _, err := collectorIface.GetFilteredEvents(ctx, filter)
+	if errors.Is(err, collector.ErrTooManyEvents) {
+		return "", fmt.Errorf("%s context can't be looked up, %s holds too many events, reduce the maximum age of the reference", reference.Ref, filter.String())
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch events of %s: %w", filter.String(), err)
+	}
+
+	candidates := []*collector.EventEnvelope{}
+	for _, event := range events {
+		if event.KeptnContext == "" || event.KeptnContext == currentContext {
+			continue
+		}
+
+		if reference.Result != "" && string(event.Data.Result) != reference.Result {
+			continue
+		}
+
+		if reference.Ref == ContextRefLabel {
+			value, ok := event.Data.Labels[reference.Label]
+			if !ok || value != labelValue {
+				continue
+			}
+		}
+
+		candidates = append(candidates, event)
+	}
+
+	latest, err := collectorIface.SelectEvent(candidates, collector.SelectionStrategy{Name: collector.StrategyLast})
+	if err != nil {
+		if reference.Ref == ContextRefLabel {
+			return "", fmt.Errorf("no context labeled %s=\"%s\" found for %s", reference.Label, labelValue, filter.String())
+		}
+
+		return "", fmt.Errorf("no context found for %s", filter.String())
+	}
+
+	return latest.KeptnContext, nil
+}
+
+/**
+ * Resolves the context that triggered the current one. The event triggering the sequence of the
+ * current context, e.g. sh.keptn.event.hardening.delivery.triggered, references the event it was
+ * triggered by in its triggeredid, which is looked up among the events of the project and service
+ * sent up to the lookback before the sequence was triggered.
+ */
+func resolveTriggeringContext(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	currentContext string,
+	eventData keptnv2.EventData,
+	lookback time.Duration,
+) (string, error) {
+	contextEvents, _, err := collectorIface.GetFilteredEvents(ctx, collector.EventFilter{KeptnContext: currentContext})
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch events of context %s: %w", currentContext, err)
+	}
+
+	var sequenceTriggered *collector.EventEnvelope
+	for _, event := range contextEvents {
+		if !isSequenceTriggeredEvent(event.Event.Type(), event.Data.Stage) {
+			continue
+		}
+
+		if sequenceTriggered == nil || event.Time.Before(sequenceTriggered.Time) {
+			sequenceTriggered = event
+		}
+	}
+
+	if sequenceTriggered == nil {
+		return "", fmt.Errorf("no sequence triggered in context %s", currentContext)
+	}
+
+	if sequenceTriggered.TriggeredId == "" {
+		return "", fmt.Errorf("sequence of context %s was not triggered by another event", currentContext)
+	}
+
+	filter := collector.EventFilter{
+		Project:  eventData.Project,
+		Service:  eventData.Service,
+		FromTime: sequenceTriggered.Time.Add(-lookback),
+		ToTime:   sequenceTriggered.Time,
+	}
+
+	events, _, err := collectorIface.GetFilteredEvents(ctx, filter)
+	if errors.Is(err, collector.ErrTooManyEvents) {
+		return "", fmt.Errorf("event %s triggering context %s can't be looked up, %s holds too many events, reduce the maximum age of the reference", sequenceTriggered.TriggeredId, currentContext, filter.String())
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch events of %s: %w", filter.String(), err)
+	}
+
+	for _, event := range events {
+		if event.Event.ID() == sequenceTriggered.TriggeredId && event.KeptnContext != "" && event.KeptnContext != currentContext {
+			return event.KeptnContext, nil
+		}
+	}
+
+	return "", fmt.Errorf("event %s triggering context %s not found in another context of %s", sequenceTriggered.TriggeredId, currentContext, filter.String())
+}
+
+/**
+ * Replaces references to labels of the incoming event, e.g. $LABEL.buildId, by their values.
+ */
+func expandLabelReferences(value string, labels map[string]string) (string, error) {
+	var err error
+
+	expanded := labelReferencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		key := labelReferencePattern.FindStringSubmatch(match)[1]
+
+		labelValue, ok := labels[key]
+		if !ok && err == nil {
+			err = fmt.Errorf("label \"%s\" of the incoming event not found", key)
+		}
+
+		return labelValue
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return expanded, nil
+}
@@ -0,0 +1,139 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
+	"gotest.tools/assert"
+)
+
+func TestResolveTriggeringContext(t *testing.T) {
+	const (
+		currentContext    = "0dc1538a-2550-49b5-8319-30d57a83519f"
+		triggeringContext = "c7d9e0f1-5a2b-4c3d-8e4f-6a7b8c9d0e12"
+	)
+
+	sequenceTriggeredAt := time.Date(2022, 4, 7, 10, 55, 0, 0, time.UTC)
+	eventData := keptnv2.EventData{Project: "simplenode-gitlab", Stage: "staging", Service: "simplenodeservice"}
+
+	sequenceTriggered := cloudevents.NewEvent()
+	sequenceTriggered.SetID("current-delivery-triggered")
+	sequenceTriggered.SetType("sh.keptn.event.staging.delivery.triggered")
+
+	triggering := cloudevents.NewEvent()
+	triggering.SetID("latest-delivery-finished")
+
+	lookupFilter := collector.EventFilter{
+		Project:  "simplenode-gitlab",
+		Service:  "simplenodeservice",
+		FromTime: sequenceTriggeredAt.Add(-time.Hour),
+		ToTime:   sequenceTriggeredAt,
+	}
+
+	tests := []struct {
+		name            string
+		lookupEvents    []*collector.EventEnvelope
+		lookupError     error
+		expectedContext string
+		expectedError   string
+	}{
+		{
+			name:            "found",
+			lookupEvents:    []*collector.EventEnvelope{{Event: triggering, KeptnContext: triggeringContext}},
+			expectedContext: triggeringContext,
+		},
+		{
+			name:          "not found",
+			lookupEvents:  []*collector.EventEnvelope{},
+			expectedError: "event latest-delivery-finished triggering context 0dc1538a-2550-49b5-8319-30d57a83519f not found in another context of project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-04-07T09:55:00Z, to 2022-04-07T10:55:00Z",
+		},
+		{
+			name:          "too many events",
+			lookupError:   fmt.Errorf("%w: project \"simplenode-gitlab\" exceeds the maximum of 10000 events", collector.ErrTooManyEvents),
+			expectedError: "event latest-delivery-finished triggering context 0dc1538a-2550-49b5-8319-30d57a83519f can't be looked up, project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-04-07T09:55:00Z, to 2022-04-07T10:55:00Z holds too many events, reduce the maximum age of the reference",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := NewMockCollectorIface(ctrl)
+
+			m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{KeptnContext: currentContext}).Return([]*collector.EventEnvelope{
+				{Event: sequenceTriggered, KeptnContext: currentContext, TriggeredId: "latest-delivery-finished", Time: sequenceTriggeredAt, Data: eventData},
+			}, []collector.DecodeWarning{}, nil).Times(1)
+			m.EXPECT().GetFilteredEvents(gomock.Any(), lookupFilter).Return(test.lookupEvents, []collector.DecodeWarning{}, test.lookupError).Times(1)
+
+			resolvedContext, err := resolveTriggeringContext(context.Background(), m, currentContext, eventData, time.Hour)
+			if test.expectedError != "" {
+				assert.Error(t, err, test.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.Equal(t, resolvedContext, test.expectedContext)
+		})
+	}
+}
+
+func TestResolveContextReference(t *testing.T) {
+	const currentContext = "0dc1538a-2550-49b5-8319-30d57a83519f"
+
+	lookupTime := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+	eventData := keptnv2.EventData{Project: "simplenode-gitlab", Stage: "staging", Service: "simplenodeservice"}
+
+	incomingEvent := cloudevents.NewEvent()
+	incomingEvent.SetExtension("shkeptncontext", currentContext)
+
+	tests := []struct {
+		name           string
+		reference      ContextReference
+		expectedFilter collector.EventFilter
+		lookupError    error
+		expectedError  string
+	}{
+		{
+			name:           "default maximum age",
+			reference:      ContextReference{Ref: ContextRefLatest, Stage: "hardening"},
+			expectedFilter: collector.EventFilter{Project: "simplenode-gitlab", Stage: "hardening", Service: "simplenodeservice", FromTime: lookupTime.Add(-720 * time.Hour)},
+			expectedError:  "no context found for project \"simplenode-gitlab\", stage \"hardening\", service \"simplenodeservice\", from 2022-03-08T12:00:00Z",
+		},
+		{
+			name:           "too many events",
+			reference:      ContextReference{Ref: ContextRefLatest, Stage: "hardening", MaxAge: "168h"},
+			expectedFilter: collector.EventFilter{Project: "simplenode-gitlab", Stage: "hardening", Service: "simplenodeservice", FromTime: lookupTime.Add(-168 * time.Hour)},
+			lookupError:    fmt.Errorf("%w: project \"simplenode-gitlab\" exceeds the maximum of 10000 events", collector.ErrTooManyEvents),
+			expectedError:  "latest context can't be looked up, project \"simplenode-gitlab\", stage \"hardening\", service \"simplenodeservice\", from 2022-03-31T12:00:00Z holds too many events, reduce the maximum age of the reference",
+		},
+		{
+			name:           "failed lookup",
+			reference:      ContextReference{Ref: ContextRefLabel, Label: "buildId", Value: "1.0.0"},
+			expectedFilter: collector.EventFilter{Project: "simplenode-gitlab", Service: "simplenodeservice", FromTime: lookupTime.Add(-720 * time.Hour)},
+			lookupError:    fmt.Errorf("connection refused"),
+			expectedError:  "failed to fetch events of project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-03-08T12:00:00Z: connection refused",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			m := NewMockCollectorIface(ctrl)
+
+			m.EXPECT().GetFilteredEvents(gomock.Any(), test.expectedFilter).Return([]*collector.EventEnvelope{}, []collector.DecodeWarning{}, test.lookupError).Times(1)
+			m.EXPECT().SelectEvent(gomock.Any(), gomock.Any()).Return(nil, fmt.Errorf("no events")).AnyTimes()
+
+			_, err := resolveContextReference(context.Background(), m, incomingEvent, eventData, test.reference, lookupTime)
+			assert.Error(t, err, test.expectedError)
+		})
+	}
+}
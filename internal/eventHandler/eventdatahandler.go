@@ -1,25 +1,339 @@
 package eventHandler
 
 import (
+	"encoding/json"
 	"fmt"
-	"log"
+	"regexp"
+	"strings"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
 )
 
 const defaultSyntheticTestFinishedEventType = "sh.keptn.event.test.finished"
 
+// Scopes events of the current context are selected from if no stage filter was provided.
+const (
+	ScopeContext  = "context"
+	ScopeStage    = "stage"
+	ScopeSequence = "sequence"
+)
+
+// Modes the boundaries of the evaluation window are determined by. Boundaries are taken from events
+// by default. The end can be the time of collection or of the triggering event instead, and the
+// start can be a fixed duration before the end.
+const (
+	ModeEvent     = "event"
+	ModeNow       = "now"
+	ModeTriggered = "triggered"
+	ModeDuration  = "duration"
+)
+
+// EventTypes is either a single event type or a list of event types. Every type is either a literal,
+// a glob such as sh.keptn.event.*.started or a regular expression enclosed in slashes.
+type EventTypes []string
+
+func (t *EventTypes) UnmarshalJSON(data []byte) error {
+	eventType := ""
+	if json.Unmarshal(data, &eventType) == nil {
+		*t = EventTypes{}
+		if eventType != "" {
+			*t = EventTypes{eventType}
+		}
+
+		return nil
+	}
+
+	eventTypes := []string{}
+	err := json.Unmarshal(data, &eventTypes)
+	if err != nil {
+		return fmt.Errorf("expected event type or list of event types")
+	}
+
+	*t = eventTypes
+
+	return nil
+}
+
+/**
+ * Decodes a value given either as string, which is passed to fromString, or as object. The object
+ * has to be an alias of the decoded type to not recurse into its UnmarshalJSON.
+ */
+func unmarshalStringOrObject(data []byte, fromString func(string), object interface{}, expected string) error {
+	value := ""
+	if json.Unmarshal(data, &value) == nil {
+		fromString(value)
+		return nil
+	}
+
+	err := json.Unmarshal(data, object)
+	if err != nil {
+		return fmt.Errorf("expected %s", expected)
+	}
+
+	return nil
+}
+
+// SelectionStrategy picks the event a boundary timestamp is taken from. It is either the name of a
+// strategy or an object with its parameters, e.g. {"name": "nth", "n": 2}.
+type SelectionStrategy struct {
+	Name   string     `json:"name"`
+	N      int        `json:"n,omitempty"`
+	Anchor EventTypes `json:"anchor,omitempty"`
+}
+
+func (s *SelectionStrategy) UnmarshalJSON(data []byte) error {
+	type selectionStrategy SelectionStrategy
+	*s = SelectionStrategy{}
+
+	return unmarshalStringOrObject(data, func(name string) { s.Name = name }, (*selectionStrategy)(s), "strategy name or object")
+}
+
+/**
+ * Describes the strategy for the finished event, e.g. nth 2 or after sh.keptn.event.deployment.finished
+ */
+func (s SelectionStrategy) String() string {
+	switch s.Name {
+	case collector.StrategyNth:
+		return fmt.Sprintf("%s %d", s.Name, s.N)
+	case collector.StrategyAfter:
+		return fmt.Sprintf("%s %s", s.Name, strings.Join(s.Anchor, ", "))
+	default:
+		return s.Name
+	}
+}
+
+// Rounding rounds a boundary timestamp. It is either a mode or an object with mode and granularity,
+// e.g. {"mode": "nearest", "granularity": "30s"}.
+type Rounding struct {
+	Mode        string `json:"mode"`
+	Granularity string `json:"granularity,omitempty"`
+}
+
+func (r *Rounding) UnmarshalJSON(data []byte) error {
+	type rounding Rounding
+	*r = Rounding{}
+
+	return unmarshalStringOrObject(data, func(mode string) { r.Mode = mode }, (*rounding)(r), "rounding mode or object")
+}
+
+const defaultRoundingGranularity = time.Minute
+
+// TimestampSource selects where a boundary timestamp is read from. It is either a source or an
+// object with source and path, e.g. {"source": "field", "path": "test.start"}.
+type TimestampSource struct {
+	Source string `json:"source"`
+	Path   string `json:"path,omitempty"`
+}
+
+func (t *TimestampSource) UnmarshalJSON(data []byte) error {
+	type timestampSource TimestampSource
+	*t = TimestampSource{}
+
+	return unmarshalStringOrObject(data, func(source string) { t.Source = source }, (*timestampSource)(t), "timestamp source or object")
+}
+
+// WindowOptions pad the evaluation window and bound its duration. Durations are e.g. "30s" or "2h".
+type WindowOptions struct {
+	PrePadding  string `json:"prePadding"`
+	PostPadding string `json:"postPadding"`
+	MinDuration string `json:"minDuration"`
+	MinGrowth   string `json:"minGrowth"`
+	MaxDuration string `json:"maxDuration"`
+	MaxPolicy   string `json:"maxPolicy"`
+}
+
+// Symbolic references to a Keptn context
+const (
+	ContextRefLatest     = "latest"
+	ContextRefLabel      = "label"
+	ContextRefTriggering = "triggering"
+)
+
+// ContextReference is either a literal Keptn context ID or a reference resolved through the event
+// source, e.g. the latest context of the service in a stage whose test finished,
+// {"ref": "latest", "stage": "hardening", "eventType": "sh.keptn.event.test.finished"}, the
+// context whose label matches one of the incoming event, {"ref": "label", "label": "buildId",
+// "value": "$LABEL.buildId"}, or the context that triggered the current one, {"ref": "triggering"}.
+type ContextReference struct {
+	ID        string     `json:"-"`
+	Ref       string     `json:"ref"`
+	EventType EventTypes `json:"eventType"`
+	Stage     string     `json:"stage"`
+	Result    string     `json:"result"`
+	Label     string     `json:"label"`
+	Value     string     `json:"value"`
+	MaxAge    string     `json:"maxAge"`
+}
+
+func (r *ContextReference) UnmarshalJSON(data []byte) error {
+	type contextReference ContextReference
+	*r = ContextReference{}
+
+	return unmarshalStringOrObject(data, func(id string) { r.ID = id }, (*contextReference)(r), "context ID or context reference object")
+}
+
+/**
+ * Checks whether a context ID or reference was provided.
+ */
+func (r ContextReference) IsSet() bool {
+	return r.ID != "" || r.Ref != ""
+}
+
+/**
+ * Returns the context ID. References have to be resolved beforehand, see ResolveContextReferences.
+ */
+func (r ContextReference) Resolved() (string, error) {
+	if r.ID == "" {
+		return "", fmt.Errorf("context reference \"%s\" was not resolved", r.Ref)
+	}
+
+	return r.ID, nil
+}
+
+func (r ContextReference) validate() error {
+	switch r.Ref {
+	case ContextRefLatest, ContextRefTriggering:
+	case ContextRefLabel:
+		if r.Label == "" {
+			return fmt.Errorf("context reference \"%s\" requires a label", r.Ref)
+		}
+	default:
+		return fmt.Errorf("unknown context reference \"%s\"", r.Ref)
+	}
+
+	_, err := parseWindowDuration("context reference maximum age", r.MaxAge)
+	return err
+}
+
+// Trim cuts a warm-up or cool-down phase off the evaluation window. It is either a duration, e.g.
+// "5m", or a phase marker event, e.g. {"eventType": "sh.keptn.event.test.status.changed",
+// "label": "phase", "value": "steady"}.
+type Trim struct {
+	Duration  string     `json:"-"`
+	EventType EventTypes `json:"eventType"`
+	Label     string     `json:"label"`
+	Value     string     `json:"value"`
+}
+
+func (t *Trim) UnmarshalJSON(data []byte) error {
+	type trim Trim
+	*t = Trim{}
+
+	return unmarshalStringOrObject(data, func(duration string) { t.Duration = duration }, (*trim)(t), "duration or phase marker object")
+}
+
+type TrimOptions struct {
+	WarmUp   *Trim `json:"warmUp"`
+	CoolDown *Trim `json:"coolDown"`
+}
+
+// PhaseTrim is a parsed Trim. Phases are trimmed either by the duration or up to a marker event of
+// one of the marker types, optionally carrying the label.
+type PhaseTrim struct {
+	Duration    time.Duration
+	MarkerTypes EventTypes
+	Label       string
+	Value       string
+}
+
+func (t PhaseTrim) HasMarker() bool {
+	return len(t.MarkerTypes) > 0
+}
+
+// SplitOptions slice the evaluation window into either Count equal sub-windows or sub-windows of
+// Duration, e.g. "15m".
+type SplitOptions struct {
+	Count    int    `json:"count"`
+	Duration string `json:"duration"`
+}
+
+// ValidationOptions configure the sanity checks of the evaluation window.
+type ValidationOptions struct {
+	Policy     string `json:"policy"`
+	FutureSkew string `json:"futureSkew"`
+	MaxAge     string `json:"maxAge"`
+}
+
+const defaultFutureSkew = time.Minute
+
+// EventQuery selects events by project, stage, service, type and time range instead of by
+// Keptn context. From and To are either RFC3339 timestamps or durations before now, e.g. "2h".
+type EventQuery struct {
+	Project   string     `json:"project"`
+	Stage     string     `json:"stage"`
+	Service   string     `json:"service"`
+	EventType EventTypes `json:"eventType"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+}
+
+// How far back runs are looked up for a baseline by default
+const defaultBaselineMaxAge = 30 * 24 * time.Hour
+
+// BaselineOptions select the finished events of previous runs the baseline is taken from. Project
+// and service are the ones of the incoming event, the stage defaults to the one of the incoming
+// event. MaxAge limits how far back runs are looked up, e.g. "168h".
+type BaselineOptions struct {
+	EventType EventTypes `json:"eventType"`
+	Stage     string     `json:"stage"`
+	MaxAge    string     `json:"maxAge"`
+}
+
+// NamedWindow is an additional evaluation window with its own context, filters and strategies, e.g.
+// {"name": "load", "evaluationStartEventType": "sh.keptn.event.load-test.started"}.
+type NamedWindow struct {
+	Name string `json:"name"`
+	CollectionData
+}
+
+// NamedCollection holds the collection options of a named window.
+type NamedCollection struct {
+	Name string
+	Data CollectionEventDataIface
+}
+
+// Names of windows are restricted to characters usable in label keys
+var windowNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
 type CollectionData struct {
-	EvaluationStartContext         string `json:"evaluationStartContext"`
-	EvaluationStartEventType       string `json:"evaluationStartEventType"`
-	EvaluationStartStage           string `json:"evaluationStartStage"`
-	EvaluationEndContext           string `json:"evaluationEndContext"`
-	EvaluationEndEventType         string `json:"evaluationEndEventType"`
-	EvaluationEndStage             string `json:"evaluationEndStage"`
-	SyntheticTestFinishedContext   string `json:"syntheticTestFinishedContext"`
-	SyntheticTestFinishedEventType string `json:"syntheticTestFinishedEventType"`
-	SyntheticTestFinishedStage     string `json:"syntheticTestFinishedStage"`
+	EvaluationStartContext          ContextReference   `json:"evaluationStartContext"`
+	EvaluationStartEventType        EventTypes         `json:"evaluationStartEventType"`
+	EvaluationStartStage            string             `json:"evaluationStartStage"`
+	EvaluationStartQuery            *EventQuery        `json:"evaluationStartQuery"`
+	EvaluationStartExecution        string             `json:"evaluationStartExecution"`
+	EvaluationStartExpression       string             `json:"evaluationStartExpression"`
+	EvaluationStartStrategy         *SelectionStrategy `json:"evaluationStartStrategy"`
+	EvaluationStartRounding         *Rounding          `json:"evaluationStartRounding"`
+	EvaluationStartMode             string             `json:"evaluationStartMode"`
+	EvaluationStartTimestamp        *TimestampSource   `json:"evaluationStartTimestamp"`
+	EvaluationEndContext            ContextReference   `json:"evaluationEndContext"`
+	EvaluationEndEventType          EventTypes         `json:"evaluationEndEventType"`
+	EvaluationEndStage              string             `json:"evaluationEndStage"`
+	EvaluationEndQuery              *EventQuery        `json:"evaluationEndQuery"`
+	EvaluationEndExecution          string             `json:"evaluationEndExecution"`
+	EvaluationEndExpression         string             `json:"evaluationEndExpression"`
+	EvaluationEndStrategy           *SelectionStrategy `json:"evaluationEndStrategy"`
+	EvaluationEndRounding           *Rounding          `json:"evaluationEndRounding"`
+	EvaluationEndMode               string             `json:"evaluationEndMode"`
+	EvaluationEndTimestamp          *TimestampSource   `json:"evaluationEndTimestamp"`
+	EvaluationDuration              string             `json:"evaluationDuration"`
+	SyntheticTestFinishedContext    ContextReference   `json:"syntheticTestFinishedContext"`
+	SyntheticTestFinishedEventType  EventTypes         `json:"syntheticTestFinishedEventType"`
+	SyntheticTestFinishedStage      string             `json:"syntheticTestFinishedStage"`
+	SyntheticTestFinishedQuery      *EventQuery        `json:"syntheticTestFinishedQuery"`
+	SyntheticTestFinishedExecution  string             `json:"syntheticTestFinishedExecution"`
+	SyntheticTestFinishedExpression string             `json:"syntheticTestFinishedExpression"`
+	Window                          *WindowOptions     `json:"window"`
+	Trim                            *TrimOptions       `json:"trim"`
+	Split                           *SplitOptions      `json:"split"`
+	Validation                      *ValidationOptions `json:"validation"`
+	BypassCache                     bool               `json:"bypassCache"`
+	Scope                           string             `json:"scope"`
+	Windows                         []NamedWindow      `json:"windows"`
+	Baseline                        *BaselineOptions   `json:"baseline"`
 }
 
 type CollectionEventData struct {
@@ -31,24 +345,51 @@ type CollectionEventData struct {
 type CollectionEventDataIface interface {
 	GetEventData() keptnv2.EventData
 	GetEvaluationStartContext() (string, error)
-	GetEvaluationStartEventFilter() string
+	GetEvaluationStartEventFilter() EventTypes
 	GetEvaluationStartStageFilter() string
 	GetEvaluationEndContext() (string, error)
-	GetEvaluationEndEventFilter() string
+	GetEvaluationEndEventFilter() EventTypes
 	GetEvaluationEndStageFilter() string
 	GetSyntheticTestFinishedContext() (string, error)
-	GetSyntheticTestFinishedEventFilter() string
+	GetSyntheticTestFinishedEventFilter() EventTypes
 	GetSyntheticTestFinishedStageFilter() string
+	GetEvaluationStartFilter(now time.Time) (collector.EventFilter, error)
+	GetEvaluationEndFilter(now time.Time) (collector.EventFilter, error)
+	GetSyntheticTestFinishedFilter(now time.Time) (collector.EventFilter, error)
+	GetEvaluationStartExecution() string
+	GetEvaluationEndExecution() string
+	GetSyntheticTestFinishedExecution() string
+	GetEvaluationStartExpression() string
+	GetEvaluationEndExpression() string
+	GetSyntheticTestFinishedExpression() string
+	GetEvaluationStartStrategy() (SelectionStrategy, error)
+	GetEvaluationEndStrategy() (SelectionStrategy, error)
+	GetEvaluationStartRounding() (collector.Rounding, error)
+	GetEvaluationEndRounding() (collector.Rounding, error)
+	GetEvaluationStartTimestampSource() (collector.TimestampSource, error)
+	GetEvaluationEndTimestampSource() (collector.TimestampSource, error)
+	GetEvaluationStartMode() (string, error)
+	GetEvaluationEndMode() (string, error)
+	GetEvaluationDuration() (time.Duration, error)
+	GetWindowConstraints() (collector.WindowConstraints, error)
+	GetWindowValidation() (collector.WindowValidation, error)
+	GetWarmUpTrim() (PhaseTrim, error)
+	GetCoolDownTrim() (PhaseTrim, error)
+	GetWindowSplit() (collector.WindowSplit, error)
+	GetBypassCache() bool
+	GetScope() (string, error)
+	GetNamedWindows() ([]NamedCollection, error)
+	ResolveContextReferences(resolve func(reference ContextReference) (string, error)) error
+	GetBaselineFilters(lookupTime time.Time) ([]collector.EventFilter, error)
 }
 
 /**
  * Parses a Keptn Cloud Event payload (data attribute)
  */
 func parseKeptnCloudEventPayload(event cloudevents.Event, data interface{}) error {
-	err := event.DataAs(data)
+	err := json.Unmarshal(event.Data(), data)
 	if err != nil {
-		log.Fatalf("Got Data Error: %s", err.Error())
-		return err
+		return fmt.Errorf("invalid collection data: %s", err.Error())
 	}
 	return nil
 }
@@ -60,14 +401,49 @@ func (collectionEventData *CollectionEventData) GetEventData() keptnv2.EventData
 	return collectionEventData.EventData
 }
 
+/**
+ * Resolves the symbolic evaluation start, evaluation end and synthetic test finished contexts by the
+ * resolver. Literal context IDs and references resolved before are kept as is.
+ */
+func (collectionEventData *CollectionEventData) ResolveContextReferences(resolve func(reference ContextReference) (string, error)) error {
+	references := []struct {
+		name      string
+		reference *ContextReference
+	}{
+		{"evaluation start", &collectionEventData.Collection.EvaluationStartContext},
+		{"evaluation end", &collectionEventData.Collection.EvaluationEndContext},
+		{"synthetic test finished", &collectionEventData.Collection.SyntheticTestFinishedContext},
+	}
+
+	for _, reference := range references {
+		if reference.reference.ID != "" || reference.reference.Ref == "" {
+			continue
+		}
+
+		err := reference.reference.validate()
+		if err != nil {
+			return fmt.Errorf("invalid %s context: %w", reference.name, err)
+		}
+
+		keptnContext, err := resolve(*reference.reference)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s context: %w", reference.name, err)
+		}
+
+		reference.reference.ID = keptnContext
+	}
+
+	return nil
+}
+
 /**
  * Parses evaluation start context. If none was provided in event payload,
  * current context will be returned.
  */
 func (collectionEventData *CollectionEventData) GetEvaluationStartContext() (string, error) {
-	isProvidedByIncomingEvent := collectionEventData.Collection.EvaluationStartContext != ""
+	isProvidedByIncomingEvent := collectionEventData.Collection.EvaluationStartContext.IsSet()
 	if isProvidedByIncomingEvent {
-		return collectionEventData.Collection.EvaluationStartContext, nil
+		return collectionEventData.Collection.EvaluationStartContext.Resolved()
 	} else {
 		shKeptnContextIface, err := collectionEventData.eventContext.GetExtension("shkeptncontext")
 		if err != nil {
@@ -84,10 +460,10 @@ func (collectionEventData *CollectionEventData) GetEvaluationStartContext() (str
 }
 
 /**
- * Parses evaluation start event type. If none was provided in event payload,
- * empty string filter will be returned.
+ * Parses evaluation start event types. If none were provided in event payload,
+ * an empty filter will be returned.
  */
-func (collectionEventData *CollectionEventData) GetEvaluationStartEventFilter() string {
+func (collectionEventData *CollectionEventData) GetEvaluationStartEventFilter() EventTypes {
 	return collectionEventData.Collection.EvaluationStartEventType
 }
 
@@ -104,10 +480,10 @@ func (collectionEventData *CollectionEventData) GetEvaluationStartStageFilter()
  * current context will be returned.
  */
 func (collectionEventData *CollectionEventData) GetEvaluationEndContext() (string, error) {
-	isProvidedByIncomingEvent := collectionEventData.Collection.EvaluationEndContext != ""
+	isProvidedByIncomingEvent := collectionEventData.Collection.EvaluationEndContext.IsSet()
 
 	if isProvidedByIncomingEvent {
-		return collectionEventData.Collection.EvaluationEndContext, nil
+		return collectionEventData.Collection.EvaluationEndContext.Resolved()
 	} else {
 		shKeptnContextIface, err := collectionEventData.eventContext.GetExtension("shkeptncontext")
 		if err != nil {
@@ -124,10 +500,10 @@ func (collectionEventData *CollectionEventData) GetEvaluationEndContext() (strin
 }
 
 /**
- * Parses evaluation end event type. If none was provided in event payload,
- * empty string filter will be returned.
+ * Parses evaluation end event types. If none were provided in event payload,
+ * an empty filter will be returned.
  */
-func (collectionEventData *CollectionEventData) GetEvaluationEndEventFilter() string {
+func (collectionEventData *CollectionEventData) GetEvaluationEndEventFilter() EventTypes {
 	return collectionEventData.Collection.EvaluationEndEventType
 }
 
@@ -144,10 +520,10 @@ func (collectionEventData *CollectionEventData) GetEvaluationEndStageFilter() st
  * empty context will be returned.
  */
 func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedContext() (string, error) {
-	isProvidedByIncomingEvent := collectionEventData.Collection.SyntheticTestFinishedContext != ""
+	isProvidedByIncomingEvent := collectionEventData.Collection.SyntheticTestFinishedContext.IsSet()
 
 	if isProvidedByIncomingEvent {
-		return collectionEventData.Collection.SyntheticTestFinishedContext, nil
+		return collectionEventData.Collection.SyntheticTestFinishedContext.Resolved()
 	} else {
 		shKeptnContextIface, err := collectionEventData.eventContext.GetExtension("shkeptncontext")
 		if err != nil {
@@ -164,16 +540,16 @@ func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedContext(
 }
 
 /**
- * Parses synthetic test finished event type. If none was provided in event payload,
+ * Parses synthetic test finished event types. If none were provided in event payload,
  * const defaultSyntheticTestFinishedEventType will be returned.
  */
-func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedEventFilter() string {
-	isProvidedByIncomingEvent := collectionEventData.Collection.SyntheticTestFinishedEventType != ""
+func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedEventFilter() EventTypes {
+	isProvidedByIncomingEvent := len(collectionEventData.Collection.SyntheticTestFinishedEventType) > 0
 
 	if isProvidedByIncomingEvent {
 		return collectionEventData.Collection.SyntheticTestFinishedEventType
 	} else {
-		return defaultSyntheticTestFinishedEventType
+		return EventTypes{defaultSyntheticTestFinishedEventType}
 	}
 }
 
@@ -185,6 +561,696 @@ func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedStageFil
 	return collectionEventData.Collection.SyntheticTestFinishedStage
 }
 
+/**
+ * Builds the filter evaluation start events are selected by. If an evaluation start query
+ * was provided in event payload, it is used in place of the evaluation start context.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartFilter(now time.Time) (collector.EventFilter, error) {
+	return collectionEventData.buildFilter(
+		collectionEventData.Collection.EvaluationStartQuery,
+		collectionEventData.Collection.EvaluationStartContext.IsSet(),
+		collectionEventData.GetEvaluationStartContext,
+		collectionEventData.GetEvaluationStartEventFilter(),
+		collectionEventData.GetEvaluationStartStageFilter(),
+		now,
+	)
+}
+
+/**
+ * Builds the filter evaluation end events are selected by. If an evaluation end query
+ * was provided in event payload, it is used in place of the evaluation end context.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndFilter(now time.Time) (collector.EventFilter, error) {
+	return collectionEventData.buildFilter(
+		collectionEventData.Collection.EvaluationEndQuery,
+		collectionEventData.Collection.EvaluationEndContext.IsSet(),
+		collectionEventData.GetEvaluationEndContext,
+		collectionEventData.GetEvaluationEndEventFilter(),
+		collectionEventData.GetEvaluationEndStageFilter(),
+		now,
+	)
+}
+
+/**
+ * Builds the filter synthetic test finished events are selected by. If a synthetic test finished
+ * query was provided in event payload, it is used in place of the synthetic test finished context.
+ */
+func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedFilter(now time.Time) (collector.EventFilter, error) {
+	return collectionEventData.buildFilter(
+		collectionEventData.Collection.SyntheticTestFinishedQuery,
+		collectionEventData.Collection.SyntheticTestFinishedContext.IsSet(),
+		collectionEventData.GetSyntheticTestFinishedContext,
+		collectionEventData.GetSyntheticTestFinishedEventFilter(),
+		collectionEventData.GetSyntheticTestFinishedStageFilter(),
+		now,
+	)
+}
+
+/**
+ * Parses the execution evaluation start events are selected from. If none was provided in event
+ * payload, events of all executions will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartExecution() string {
+	return collectionEventData.Collection.EvaluationStartExecution
+}
+
+/**
+ * Parses the execution evaluation end events are selected from. If none was provided in event
+ * payload, events of all executions will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndExecution() string {
+	return collectionEventData.Collection.EvaluationEndExecution
+}
+
+/**
+ * Parses the execution synthetic test finished events are selected from. If none was provided in
+ * event payload, events of all executions will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedExecution() string {
+	return collectionEventData.Collection.SyntheticTestFinishedExecution
+}
+
+/**
+ * Parses the expression evaluation start events are selected by. If none was provided in event
+ * payload, all events matching the evaluation start filter will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartExpression() string {
+	return collectionEventData.Collection.EvaluationStartExpression
+}
+
+/**
+ * Parses the expression evaluation end events are selected by. If none was provided in event
+ * payload, all events matching the evaluation end filter will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndExpression() string {
+	return collectionEventData.Collection.EvaluationEndExpression
+}
+
+/**
+ * Parses the expression synthetic test finished events are selected by. If none was provided in
+ * event payload, all events matching the synthetic test finished filter will be considered.
+ */
+func (collectionEventData *CollectionEventData) GetSyntheticTestFinishedExpression() string {
+	return collectionEventData.Collection.SyntheticTestFinishedExpression
+}
+
+/**
+ * Parses the strategy picking the evaluation start event. If none was provided in event payload,
+ * the first event will be picked.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartStrategy() (SelectionStrategy, error) {
+	return parseStrategy(collectionEventData.Collection.EvaluationStartStrategy, collector.StrategyFirst)
+}
+
+/**
+ * Parses the strategy picking the evaluation end event. If none was provided in event payload,
+ * the last event will be picked.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndStrategy() (SelectionStrategy, error) {
+	return parseStrategy(collectionEventData.Collection.EvaluationEndStrategy, collector.StrategyLast)
+}
+
+func parseStrategy(strategy *SelectionStrategy, defaultName string) (SelectionStrategy, error) {
+	if strategy == nil || strategy.Name == "" {
+		return SelectionStrategy{Name: defaultName}, nil
+	}
+
+	switch strategy.Name {
+	case collector.StrategyFirst, collector.StrategyLast, collector.StrategyBeforeTriggered:
+		return *strategy, nil
+	case collector.StrategyNth:
+		if strategy.N == 0 {
+			return SelectionStrategy{}, fmt.Errorf("invalid strategy \"%s\": n must not be 0", strategy.Name)
+		}
+
+		return *strategy, nil
+	case collector.StrategyAfter:
+		if len(strategy.Anchor) == 0 {
+			return SelectionStrategy{}, fmt.Errorf("invalid strategy \"%s\": anchor event type is required", strategy.Name)
+		}
+
+		return *strategy, nil
+	default:
+		return SelectionStrategy{}, fmt.Errorf("unknown strategy \"%s\"", strategy.Name)
+	}
+}
+
+/**
+ * Parses the rounding of the evaluation start. If none was provided in event payload,
+ * it is floored to the minute.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartRounding() (collector.Rounding, error) {
+	return parseRounding(collectionEventData.Collection.EvaluationStartRounding, collector.RoundingFloor)
+}
+
+/**
+ * Parses the rounding of the evaluation end. If none was provided in event payload,
+ * it is ceiled to the minute.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndRounding() (collector.Rounding, error) {
+	return parseRounding(collectionEventData.Collection.EvaluationEndRounding, collector.RoundingCeil)
+}
+
+func parseRounding(rounding *Rounding, defaultMode string) (collector.Rounding, error) {
+	if rounding == nil || rounding.Mode == "" {
+		return collector.Rounding{Mode: defaultMode, Granularity: defaultRoundingGranularity}, nil
+	}
+
+	switch rounding.Mode {
+	case collector.RoundingNone:
+		return collector.Rounding{Mode: collector.RoundingNone}, nil
+	case collector.RoundingFloor, collector.RoundingCeil, collector.RoundingNearest:
+	default:
+		return collector.Rounding{}, fmt.Errorf("unknown rounding \"%s\"", rounding.Mode)
+	}
+
+	if rounding.Granularity == "" {
+		return collector.Rounding{Mode: rounding.Mode, Granularity: defaultRoundingGranularity}, nil
+	}
+
+	granularity, err := time.ParseDuration(rounding.Granularity)
+	if err != nil || granularity <= 0 {
+		return collector.Rounding{}, fmt.Errorf("invalid rounding granularity \"%s\": expected positive duration", rounding.Granularity)
+	}
+
+	return collector.Rounding{Mode: rounding.Mode, Granularity: granularity}, nil
+}
+
+/**
+ * Returns where the evaluation start timestamp is read from. If none was provided in event payload,
+ * the CloudEvent time is used.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartTimestampSource() (collector.TimestampSource, error) {
+	return parseTimestampSource(collectionEventData.Collection.EvaluationStartTimestamp)
+}
+
+/**
+ * Returns where the evaluation end timestamp is read from. If none was provided in event payload,
+ * the CloudEvent time is used.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndTimestampSource() (collector.TimestampSource, error) {
+	return parseTimestampSource(collectionEventData.Collection.EvaluationEndTimestamp)
+}
+
+func parseTimestampSource(source *TimestampSource) (collector.TimestampSource, error) {
+	if source == nil || source.Source == "" {
+		return collector.TimestampSource{Kind: collector.TimestampCloudEvent}, nil
+	}
+
+	switch source.Source {
+	case collector.TimestampCloudEvent:
+		return collector.TimestampSource{Kind: source.Source}, nil
+	case collector.TimestampField:
+		if source.Path == "" {
+			return collector.TimestampSource{}, fmt.Errorf("invalid timestamp source \"%s\": path is required", source.Source)
+		}
+
+		return collector.TimestampSource{Kind: source.Source, Path: source.Path}, nil
+	default:
+		return collector.TimestampSource{}, fmt.Errorf("unknown timestamp source \"%s\"", source.Source)
+	}
+}
+
+/**
+ * Returns how the evaluation start is determined. If none was provided in event payload, it is
+ * taken from an event, falling back to the evaluation duration before the end if no event is found.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationStartMode() (string, error) {
+	mode := firstNonEmpty(collectionEventData.Collection.EvaluationStartMode, ModeEvent)
+
+	switch mode {
+	case ModeEvent:
+	case ModeDuration:
+		if collectionEventData.Collection.EvaluationDuration == "" {
+			return "", fmt.Errorf("evaluation start mode \"%s\" requires an evaluation duration", mode)
+		}
+	default:
+		return "", fmt.Errorf("invalid evaluation start mode \"%s\": expected %s or %s", mode, ModeEvent, ModeDuration)
+	}
+
+	return mode, nil
+}
+
+/**
+ * Returns how the evaluation end is determined. If none was provided in event payload, it is
+ * taken from an event.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationEndMode() (string, error) {
+	mode := firstNonEmpty(collectionEventData.Collection.EvaluationEndMode, ModeEvent)
+
+	switch mode {
+	case ModeEvent, ModeNow, ModeTriggered:
+	default:
+		return "", fmt.Errorf("invalid evaluation end mode \"%s\": expected %s, %s or %s", mode, ModeEvent, ModeNow, ModeTriggered)
+	}
+
+	return mode, nil
+}
+
+/**
+ * Returns the duration a relative evaluation start lies before the end. Zero if none was provided.
+ */
+func (collectionEventData *CollectionEventData) GetEvaluationDuration() (time.Duration, error) {
+	duration, err := parseWindowDuration("evaluation duration", collectionEventData.Collection.EvaluationDuration)
+	if err != nil {
+		return 0, err
+	}
+
+	if duration == 0 && collectionEventData.Collection.EvaluationDuration != "" {
+		return 0, fmt.Errorf("invalid evaluation duration \"%s\": expected positive duration", collectionEventData.Collection.EvaluationDuration)
+	}
+
+	return duration, nil
+}
+
+/**
+ * Parses padding and duration bounds of the evaluation window. If none were provided in event
+ * payload, the window is not adjusted. Windows are grown symmetrically and windows exceeding
+ * the maximum duration fail unless specified otherwise.
+ */
+func (collectionEventData *CollectionEventData) GetWindowConstraints() (collector.WindowConstraints, error) {
+	options := collectionEventData.Collection.Window
+	if options == nil {
+		return collector.WindowConstraints{}, nil
+	}
+
+	constraints := collector.WindowConstraints{
+		MinGrowth: firstNonEmpty(options.MinGrowth, collector.GrowSymmetric),
+		MaxPolicy: firstNonEmpty(options.MaxPolicy, collector.MaxPolicyFail),
+	}
+
+	switch constraints.MinGrowth {
+	case collector.GrowSymmetric, collector.GrowForward:
+	default:
+		return collector.WindowConstraints{}, fmt.Errorf("unknown minimum growth \"%s\"", constraints.MinGrowth)
+	}
+
+	switch constraints.MaxPolicy {
+	case collector.MaxPolicyFail, collector.MaxPolicyTrimStart, collector.MaxPolicyTrimEnd:
+	default:
+		return collector.WindowConstraints{}, fmt.Errorf("unknown maximum policy \"%s\"", constraints.MaxPolicy)
+	}
+
+	var err error
+
+	constraints.PrePadding, err = parseWindowDuration("pre padding", options.PrePadding)
+	if err != nil {
+		return collector.WindowConstraints{}, err
+	}
+
+	constraints.PostPadding, err = parseWindowDuration("post padding", options.PostPadding)
+	if err != nil {
+		return collector.WindowConstraints{}, err
+	}
+
+	constraints.MinDuration, err = parseWindowDuration("minimum duration", options.MinDuration)
+	if err != nil {
+		return collector.WindowConstraints{}, err
+	}
+
+	constraints.MaxDuration, err = parseWindowDuration("maximum duration", options.MaxDuration)
+	if err != nil {
+		return collector.WindowConstraints{}, err
+	}
+
+	if constraints.MaxDuration > 0 && constraints.MinDuration > constraints.MaxDuration {
+		return collector.WindowConstraints{}, fmt.Errorf("minimum duration %s exceeds maximum duration %s", constraints.MinDuration, constraints.MaxDuration)
+	}
+
+	return constraints, nil
+}
+
+/**
+ * Parses the trim of the warm-up phase. If none was provided in event payload, no warm-up is trimmed.
+ */
+func (collectionEventData *CollectionEventData) GetWarmUpTrim() (PhaseTrim, error) {
+	if collectionEventData.Collection.Trim == nil {
+		return PhaseTrim{}, nil
+	}
+
+	return parseTrim("warm-up", collectionEventData.Collection.Trim.WarmUp)
+}
+
+/**
+ * Parses the trim of the cool-down phase. If none was provided in event payload, no cool-down is trimmed.
+ */
+func (collectionEventData *CollectionEventData) GetCoolDownTrim() (PhaseTrim, error) {
+	if collectionEventData.Collection.Trim == nil {
+		return PhaseTrim{}, nil
+	}
+
+	return parseTrim("cool-down", collectionEventData.Collection.Trim.CoolDown)
+}
+
+func parseTrim(phase string, trim *Trim) (PhaseTrim, error) {
+	if trim == nil {
+		return PhaseTrim{}, nil
+	}
+
+	if trim.Duration != "" {
+		duration, err := parseWindowDuration(phase, trim.Duration)
+		if err != nil {
+			return PhaseTrim{}, err
+		}
+
+		return PhaseTrim{Duration: duration}, nil
+	}
+
+	if len(trim.EventType) == 0 {
+		return PhaseTrim{}, fmt.Errorf("invalid %s: marker event type is required", phase)
+	}
+
+	if trim.Value != "" && trim.Label == "" {
+		return PhaseTrim{}, fmt.Errorf("invalid %s: label is required for value \"%s\"", phase, trim.Value)
+	}
+
+	return PhaseTrim{
+		MarkerTypes: trim.EventType,
+		Label:       trim.Label,
+		Value:       trim.Value,
+	}, nil
+}
+
+/**
+ * Parses how the evaluation window is split into sub-windows. If none was provided in event
+ * payload, the window isn't split.
+ */
+func (collectionEventData *CollectionEventData) GetWindowSplit() (collector.WindowSplit, error) {
+	options := collectionEventData.Collection.Split
+	if options == nil {
+		return collector.WindowSplit{}, nil
+	}
+
+	if options.Count != 0 && options.Duration != "" {
+		return collector.WindowSplit{}, fmt.Errorf("invalid split: count and duration are mutually exclusive")
+	}
+
+	if options.Duration != "" {
+		duration, err := time.ParseDuration(options.Duration)
+		if err != nil || duration <= 0 {
+			return collector.WindowSplit{}, fmt.Errorf("invalid split duration \"%s\": expected positive duration", options.Duration)
+		}
+
+		return collector.WindowSplit{Duration: duration}, nil
+	}
+
+	if options.Count < 1 || options.Count > collector.MaxSubWindows {
+		return collector.WindowSplit{}, fmt.Errorf("invalid split count %d: expected 1 to %d", options.Count, collector.MaxSubWindows)
+	}
+
+	return collector.WindowSplit{Count: options.Count}, nil
+}
+
+/**
+ * Parses the sanity checks of the evaluation window. If none were provided in event payload,
+ * inverted, empty and future windows fail the collection and the age of windows is not limited.
+ */
+func (collectionEventData *CollectionEventData) GetWindowValidation() (collector.WindowValidation, error) {
+	validation := collector.WindowValidation{
+		FutureSkew: defaultFutureSkew,
+		Policy:     collector.ValidationFail,
+	}
+
+	options := collectionEventData.Collection.Validation
+	if options == nil {
+		return validation, nil
+	}
+
+	validation.Policy = firstNonEmpty(options.Policy, collector.ValidationFail)
+	switch validation.Policy {
+	case collector.ValidationFail, collector.ValidationWarn:
+	default:
+		return collector.WindowValidation{}, fmt.Errorf("unknown validation policy \"%s\"", validation.Policy)
+	}
+
+	if options.FutureSkew != "" {
+		futureSkew, err := parseWindowDuration("future skew", options.FutureSkew)
+		if err != nil {
+			return collector.WindowValidation{}, err
+		}
+
+		validation.FutureSkew = futureSkew
+	}
+
+	maxAge, err := parseWindowDuration("maximum age", options.MaxAge)
+	if err != nil {
+		return collector.WindowValidation{}, err
+	}
+
+	validation.MaxAge = maxAge
+
+	return validation, nil
+}
+
+func parseWindowDuration(name string, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid %s \"%s\": expected non-negative duration", name, value)
+	}
+
+	return duration, nil
+}
+
+/**
+ * Builds a filter either from a query or from a context. Queries default to the project and
+ * service of the incoming event, and to the event type and stage filters of the boundary.
+ * Unless the scope is context, the current context is narrowed to the stage of the incoming event.
+ */
+func (collectionEventData *CollectionEventData) buildFilter(
+	query *EventQuery,
+	isContextProvided bool,
+	getContext func() (string, error),
+	eventTypeFilter EventTypes,
+	stageFilter string,
+	now time.Time,
+) (collector.EventFilter, error) {
+	if query == nil {
+		keptnContext, err := getContext()
+		if err != nil {
+			return collector.EventFilter{}, err
+		}
+
+		scope, err := collectionEventData.GetScope()
+		if err != nil {
+			return collector.EventFilter{}, err
+		}
+
+		if !isContextProvided && stageFilter == "" && scope != ScopeContext {
+			stageFilter = collectionEventData.Stage
+		}
+
+		filter := collector.EventFilter{
+			KeptnContext: keptnContext,
+			Stage:        stageFilter,
+		}
+
+		err = filter.SetTypes(eventTypeFilter...)
+		if err != nil {
+			return collector.EventFilter{}, err
+		}
+
+		return filter, nil
+	}
+
+	filter := collector.EventFilter{
+		Project: firstNonEmpty(query.Project, collectionEventData.Project),
+		Stage:   firstNonEmpty(query.Stage, stageFilter),
+		Service: firstNonEmpty(query.Service, collectionEventData.Service),
+	}
+
+	if len(query.EventType) > 0 {
+		eventTypeFilter = query.EventType
+	}
+
+	err := filter.SetTypes(eventTypeFilter...)
+	if err != nil {
+		return collector.EventFilter{}, err
+	}
+
+	filter.FromTime, err = parseQueryTime(query.From, now)
+	if err != nil {
+		return collector.EventFilter{}, fmt.Errorf("invalid query start \"%s\": %w", query.From, err)
+	}
+
+	filter.ToTime, err = parseQueryTime(query.To, now)
+	if err != nil {
+		return collector.EventFilter{}, fmt.Errorf("invalid query end \"%s\": %w", query.To, err)
+	}
+
+	return filter, nil
+}
+
+/**
+ * Parses a query time, either an RFC3339 timestamp or a duration before now.
+ * An empty value results in an open time range.
+ */
+func parseQueryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return timestamp, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC3339 timestamp or duration")
+	}
+
+	return now.Add(-duration), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+/**
+ * Parses whether cached events must be bypassed. If none was provided in event payload,
+ * cached events will be used.
+ */
+func (collectionEventData *CollectionEventData) GetBypassCache() bool {
+	return collectionEventData.Collection.BypassCache
+}
+
+/**
+ * Parses the scope events of the current context are selected from. If none was provided in
+ * event payload, events are scoped to the stage of the incoming event.
+ */
+func (collectionEventData *CollectionEventData) GetScope() (string, error) {
+	switch collectionEventData.Collection.Scope {
+	case "":
+		return ScopeStage, nil
+	case ScopeContext, ScopeStage, ScopeSequence:
+		return collectionEventData.Collection.Scope, nil
+	default:
+		return "", fmt.Errorf("invalid scope \"%s\": expected %s, %s or %s", collectionEventData.Collection.Scope, ScopeContext, ScopeStage, ScopeSequence)
+	}
+}
+
+/**
+ * Returns the collection options of the named windows. Every window is collected like the primary
+ * window of the collection, options it doesn't set take their defaults.
+ */
+func (collectionEventData *CollectionEventData) GetNamedWindows() ([]NamedCollection, error) {
+	namedCollections := []NamedCollection{}
+	labelPrefixes := map[string]string{}
+
+	for _, window := range collectionEventData.Collection.Windows {
+		if !windowNamePattern.MatchString(window.Name) {
+			return []NamedCollection{}, fmt.Errorf("invalid window name \"%s\": expected letters, digits, - or _", window.Name)
+		}
+
+		labelPrefix := windowLabelPrefix(window.Name)
+		if otherName, ok := labelPrefixes[labelPrefix]; ok {
+			return []NamedCollection{}, fmt.Errorf("window name \"%s\" collides with window \"%s\"", window.Name, otherName)
+		}
+		labelPrefixes[labelPrefix] = window.Name
+
+		if len(window.Windows) > 0 {
+			return []NamedCollection{}, fmt.Errorf("window \"%s\" must not define windows", window.Name)
+		}
+
+		if window.Baseline != nil {
+			return []NamedCollection{}, fmt.Errorf("window \"%s\" must not define a baseline", window.Name)
+		}
+
+		namedCollections = append(namedCollections, NamedCollection{
+			Name: window.Name,
+			Data: &CollectionEventData{
+				EventData:    collectionEventData.EventData,
+				Collection:   window.CollectionData,
+				eventContext: collectionEventData.eventContext,
+			},
+		})
+	}
+
+	return namedCollections, nil
+}
+
+/**
+ * Builds the filters finished events of previous runs the baseline is taken from, looking back from
+ * the lookup time. Every literal event type gets its own filter, so it can be pushed down to the
+ * event source, patterns are combined into one filter. If no baseline was requested in event
+ * payload, nil is returned.
+ */
+func (collectionEventData *CollectionEventData) GetBaselineFilters(lookupTime time.Time) ([]collector.EventFilter, error) {
+	options := collectionEventData.Collection.Baseline
+	if options == nil {
+		return nil, nil
+	}
+
+	maxAge, err := parseWindowDuration("baseline maximum age", options.MaxAge)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxAge == 0 {
+		maxAge = defaultBaselineMaxAge
+	}
+
+	baseFilter := collector.EventFilter{
+		Project:  collectionEventData.Project,
+		Stage:    firstNonEmpty(options.Stage, collectionEventData.Stage),
+		Service:  collectionEventData.Service,
+		FromTime: lookupTime.Add(-maxAge),
+	}
+
+	eventTypes := options.EventType
+	if len(eventTypes) == 0 {
+		eventTypes = EventTypes{collector.CollectionFinishedEventType, collector.EvaluationFinishedEventType}
+	}
+
+	filters := []collector.EventFilter{}
+	patternTypes := []string{}
+	for _, eventType := range eventTypes {
+		filter := baseFilter
+
+		err := filter.SetTypes(eventType)
+		if err != nil {
+			return nil, err
+		}
+
+		if filter.Type == "" {
+			patternTypes = append(patternTypes, eventType)
+			continue
+		}
+
+		filters = append(filters, filter)
+	}
+
+	if len(patternTypes) > 0 {
+		filter := baseFilter
+
+		err := filter.SetTypes(patternTypes...)
+		if err != nil {
+			return nil, err
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+/**
+ * Returns the prefix of the labels a named window is reported in, e.g. WINDOW_LOAD_TEST for load-test.
+ */
+func windowLabelPrefix(name string) string {
+	return "WINDOW_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
 func NewEventDataHandler(
 	incomingEvent cloudevents.Event,
 ) (*CollectionEventData, error) {
@@ -1,8 +1,12 @@
 package eventHandler
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
 	"gotest.tools/assert"
 )
 
@@ -17,6 +21,46 @@ func TestNewEventDataHandler(t *testing.T) {
 	assert.NilError(t, err)
 }
 
+func TestUnmarshalStringOrObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          string
+		decoded       interface{}
+		expected      interface{}
+		expectedError string
+	}{
+		{name: "strategy name", data: `"last"`, decoded: &SelectionStrategy{}, expected: &SelectionStrategy{Name: "last"}},
+		{name: "strategy object", data: `{"name": "nth", "n": 2}`, decoded: &SelectionStrategy{}, expected: &SelectionStrategy{Name: "nth", N: 2}},
+		{name: "strategy invalid", data: `1`, decoded: &SelectionStrategy{}, expectedError: "expected strategy name or object"},
+		{name: "rounding mode", data: `"ceil"`, decoded: &Rounding{}, expected: &Rounding{Mode: "ceil"}},
+		{name: "rounding object", data: `{"mode": "floor", "granularity": "5m"}`, decoded: &Rounding{}, expected: &Rounding{Mode: "floor", Granularity: "5m"}},
+		{name: "rounding invalid", data: `[]`, decoded: &Rounding{}, expectedError: "expected rounding mode or object"},
+		{name: "timestamp source", data: `"event"`, decoded: &TimestampSource{}, expected: &TimestampSource{Source: "event"}},
+		{name: "timestamp source object", data: `{"source": "field", "path": "test.start"}`, decoded: &TimestampSource{}, expected: &TimestampSource{Source: "field", Path: "test.start"}},
+		{name: "timestamp source invalid", data: `true`, decoded: &TimestampSource{}, expectedError: "expected timestamp source or object"},
+		{name: "context ID", data: `"abc"`, decoded: &ContextReference{}, expected: &ContextReference{ID: "abc"}},
+		{name: "context reference", data: `{"ref": "latest", "stage": "dev"}`, decoded: &ContextReference{}, expected: &ContextReference{Ref: "latest", Stage: "dev"}},
+		{name: "context invalid", data: `1`, decoded: &ContextReference{}, expectedError: "expected context ID or context reference object"},
+		{name: "trim duration", data: `"5m"`, decoded: &Trim{}, expected: &Trim{Duration: "5m"}},
+		{name: "trim marker", data: `{"eventType": "sh.keptn.event.warmup.finished"}`, decoded: &Trim{}, expected: &Trim{EventType: EventTypes{"sh.keptn.event.warmup.finished"}}},
+		{name: "trim invalid", data: `1`, decoded: &Trim{}, expectedError: "expected duration or phase marker object"},
+		{name: "previous value is replaced", data: `{"mode": "floor"}`, decoded: &Rounding{Mode: "ceil", Granularity: "1h"}, expected: &Rounding{Mode: "floor"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := json.Unmarshal([]byte(test.data), test.decoded)
+			if test.expectedError != "" {
+				assert.Error(t, err, test.expectedError)
+				return
+			}
+
+			assert.NilError(t, err)
+			assert.DeepEqual(t, test.decoded, test.expected)
+		})
+	}
+}
+
 func TestGetEvaluationStartContext(t *testing.T) {
 	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-full.json")
 	if err != nil {
@@ -72,3 +116,678 @@ func TestGetEvaluationEndContext(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Equal(t, "0dc1538a-2550-49b5-8319-30d57a83519f", context)
 }
+
+func TestResolveContextReferences(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incomingEvent.DataEncoded = []byte(`{
+		"collection": {
+			"evaluationStartContext": {"ref": "latest", "stage": "hardening", "eventType": "sh.keptn.event.test.finished"},
+			"evaluationEndContext": "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz"
+		}
+	}`)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	_, err = eventDataHandler.GetEvaluationStartContext()
+	assert.Error(t, err, "context reference \"latest\" was not resolved")
+
+	resolvedReferences := []ContextReference{}
+	err = eventDataHandler.ResolveContextReferences(func(reference ContextReference) (string, error) {
+		resolvedReferences = append(resolvedReferences, reference)
+		return "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, resolvedReferences, []ContextReference{{Ref: ContextRefLatest, Stage: "hardening", EventType: EventTypes{"sh.keptn.event.test.finished"}}})
+
+	startContext, err := eventDataHandler.GetEvaluationStartContext()
+	assert.NilError(t, err)
+	assert.Equal(t, startContext, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+
+	endContext, err := eventDataHandler.GetEvaluationEndContext()
+	assert.NilError(t, err)
+	assert.Equal(t, endContext, "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz")
+
+	syntheticTestFinishedContext, err := eventDataHandler.GetSyntheticTestFinishedContext()
+	assert.NilError(t, err)
+	assert.Equal(t, syntheticTestFinishedContext, "0dc1538a-2550-49b5-8319-30d57a83519f")
+
+	eventDataHandler.Collection.EvaluationEndContext = ContextReference{Ref: ContextRefLabel}
+	err = eventDataHandler.ResolveContextReferences(func(reference ContextReference) (string, error) {
+		return "", nil
+	})
+	assert.Error(t, err, "invalid evaluation end context: context reference \"label\" requires a label")
+
+	eventDataHandler.Collection.EvaluationEndContext = ContextReference{Ref: "previous"}
+	err = eventDataHandler.ResolveContextReferences(func(reference ContextReference) (string, error) {
+		return "", nil
+	})
+	assert.Error(t, err, "invalid evaluation end context: unknown context reference \"previous\"")
+
+	eventDataHandler.Collection.EvaluationEndContext = ContextReference{Ref: ContextRefTriggering}
+	err = eventDataHandler.ResolveContextReferences(func(reference ContextReference) (string, error) {
+		return "", fmt.Errorf("no sequence triggered")
+	})
+	assert.Error(t, err, "failed to resolve evaluation end context: no sequence triggered")
+}
+
+func TestGetBypassCache(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-full.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+	assert.Equal(t, eventDataHandler.GetBypassCache(), true)
+
+	_, incomingEvent, err = initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err = NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+	assert.Equal(t, eventDataHandler.GetBypassCache(), false)
+}
+
+func TestGetFiltersFromQuery(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-query.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	now := time.Date(2022, 4, 7, 12, 5, 28, 0, time.UTC)
+
+	filter, err := eventDataHandler.GetEvaluationStartFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		Project:  "simplenode-gitlab",
+		Stage:    "staging",
+		Service:  "simplenodeservice",
+		Type:     "sh.keptn.event.test.started",
+		FromTime: time.Date(2022, 4, 7, 10, 5, 28, 0, time.UTC),
+	})
+
+	filter, err = eventDataHandler.GetEvaluationEndFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		Project:  "other-project",
+		Stage:    "production",
+		Service:  "other-service",
+		Type:     "sh.keptn.event.test.finished",
+		FromTime: time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC),
+		ToTime:   time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC),
+	})
+
+	filter, err = eventDataHandler.GetSyntheticTestFinishedFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Stage:        "staging",
+		Type:         "sh.keptn.event.test.finished",
+	})
+
+	eventDataHandler.Collection.EvaluationEndQuery.EventType = EventTypes{"sh.keptn.event.test.finished", "sh.keptn.event.load-test.finished"}
+	filter, err = eventDataHandler.GetEvaluationEndFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		Project:     "other-project",
+		Stage:       "production",
+		Service:     "other-service",
+		TypePattern: "^(?:sh\\.keptn\\.event\\.test\\.finished|sh\\.keptn\\.event\\.load-test\\.finished)$",
+		FromTime:    time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC),
+		ToTime:      time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC),
+	})
+
+	eventDataHandler.Collection.EvaluationStartQuery.From = "yesterday"
+	_, err = eventDataHandler.GetEvaluationStartFilter(now)
+	assert.Error(t, err, "invalid query start \"yesterday\": expected RFC3339 timestamp or duration")
+}
+
+func TestGetFiltersWithScope(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	now := time.Date(2022, 4, 7, 12, 5, 28, 0, time.UTC)
+
+	filter, err := eventDataHandler.GetEvaluationStartFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Stage:        "staging",
+	})
+
+	eventDataHandler.Collection.EvaluationEndStage = "production"
+	filter, err = eventDataHandler.GetEvaluationEndFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Stage:        "production",
+	})
+
+	eventDataHandler.Collection.Scope = ScopeContext
+	filter, err = eventDataHandler.GetSyntheticTestFinishedFilter(now)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filter, collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Type:         "sh.keptn.event.test.finished",
+	})
+
+	eventDataHandler.Collection.Scope = "project"
+	_, err = eventDataHandler.GetEvaluationStartFilter(now)
+	assert.Error(t, err, "invalid scope \"project\": expected context, stage or sequence")
+
+	_, incomingEvent, err = initializeTestObjects("../../test-events/collection.triggered-full.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err = NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	filter, err = eventDataHandler.GetEvaluationStartFilter(now)
+	assert.NilError(t, err)
+	assert.Equal(t, filter.Stage, "")
+}
+
+func TestGetFiltersWithEventTypePatterns(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incomingEvent.DataEncoded = []byte(`{
+		"project": "simplenode-gitlab",
+		"service": "simplenodeservice",
+		"stage": "staging",
+		"collection": {
+			"evaluationStartEventType": ["sh.keptn.event.test.started", "sh.keptn.event.load-test.started"],
+			"evaluationEndEventType": "sh.keptn.event.(test|load-test).finished",
+			"syntheticTestFinishedEventType": ""
+		}
+	}`)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	now := time.Date(2022, 4, 7, 12, 5, 28, 0, time.UTC)
+
+	filter, err := eventDataHandler.GetEvaluationStartFilter(now)
+	assert.NilError(t, err)
+	assert.Equal(t, filter.Type, "")
+	assert.Equal(t, filter.TypePattern, `^(?:sh\.keptn\.event\.test\.started|sh\.keptn\.event\.load-test\.started)$`)
+
+	filter, err = eventDataHandler.GetEvaluationEndFilter(now)
+	assert.NilError(t, err)
+	assert.Equal(t, filter.TypePattern, `^(?:sh\.keptn\.event\.(?:test|load-test)\.finished)$`)
+
+	filter, err = eventDataHandler.GetSyntheticTestFinishedFilter(now)
+	assert.NilError(t, err)
+	assert.Equal(t, filter.Type, "sh.keptn.event.test.finished")
+	assert.Equal(t, filter.TypePattern, "")
+}
+
+func TestGetStrategies(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	strategy, err := eventDataHandler.GetEvaluationStartStrategy()
+	assert.NilError(t, err)
+	assert.Equal(t, strategy.Name, collector.StrategyFirst)
+
+	strategy, err = eventDataHandler.GetEvaluationEndStrategy()
+	assert.NilError(t, err)
+	assert.Equal(t, strategy.Name, collector.StrategyLast)
+
+	incomingEvent.DataEncoded = []byte(`{
+		"collection": {
+			"evaluationStartStrategy": {"name": "after", "anchor": ["sh.keptn.event.deployment.finished"]},
+			"evaluationEndStrategy": {"name": "nth"}
+		}
+	}`)
+
+	eventDataHandler, err = NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	strategy, err = eventDataHandler.GetEvaluationStartStrategy()
+	assert.NilError(t, err)
+	assert.Equal(t, strategy.String(), "after sh.keptn.event.deployment.finished")
+
+	_, err = eventDataHandler.GetEvaluationEndStrategy()
+	assert.Error(t, err, "invalid strategy \"nth\": n must not be 0")
+
+	eventDataHandler.Collection.EvaluationEndStrategy = &SelectionStrategy{Name: "median"}
+	_, err = eventDataHandler.GetEvaluationEndStrategy()
+	assert.Error(t, err, "unknown strategy \"median\"")
+}
+
+func TestGetRoundings(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	rounding, err := eventDataHandler.GetEvaluationStartRounding()
+	assert.NilError(t, err)
+	assert.Equal(t, rounding, collector.Rounding{Mode: collector.RoundingFloor, Granularity: time.Minute})
+
+	eventDataHandler.Collection.EvaluationEndRounding = &Rounding{Mode: collector.RoundingNearest, Granularity: "30s"}
+	rounding, err = eventDataHandler.GetEvaluationEndRounding()
+	assert.NilError(t, err)
+	assert.Equal(t, rounding, collector.Rounding{Mode: collector.RoundingNearest, Granularity: 30 * time.Second})
+
+	eventDataHandler.Collection.EvaluationEndRounding = &Rounding{Mode: collector.RoundingCeil, Granularity: "-1m"}
+	_, err = eventDataHandler.GetEvaluationEndRounding()
+	assert.Error(t, err, "invalid rounding granularity \"-1m\": expected positive duration")
+
+	eventDataHandler.Collection.EvaluationEndRounding = &Rounding{Mode: "up"}
+	_, err = eventDataHandler.GetEvaluationEndRounding()
+	assert.Error(t, err, "unknown rounding \"up\"")
+}
+
+func TestGetWindowConstraints(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	constraints, err := eventDataHandler.GetWindowConstraints()
+	assert.NilError(t, err)
+	assert.Equal(t, constraints, collector.WindowConstraints{})
+
+	eventDataHandler.Collection.Window = &WindowOptions{PrePadding: "30s", MinDuration: "1m", MaxDuration: "2h", MaxPolicy: collector.MaxPolicyTrimStart}
+	constraints, err = eventDataHandler.GetWindowConstraints()
+	assert.NilError(t, err)
+	assert.Equal(t, constraints, collector.WindowConstraints{
+		PrePadding:  30 * time.Second,
+		MinDuration: time.Minute,
+		MinGrowth:   collector.GrowSymmetric,
+		MaxDuration: 2 * time.Hour,
+		MaxPolicy:   collector.MaxPolicyTrimStart,
+	})
+
+	eventDataHandler.Collection.Window = &WindowOptions{PostPadding: "soon"}
+	_, err = eventDataHandler.GetWindowConstraints()
+	assert.Error(t, err, "invalid post padding \"soon\": expected non-negative duration")
+
+	eventDataHandler.Collection.Window = &WindowOptions{MinDuration: "2h", MaxDuration: "1h"}
+	_, err = eventDataHandler.GetWindowConstraints()
+	assert.Error(t, err, "minimum duration 2h0m0s exceeds maximum duration 1h0m0s")
+
+	eventDataHandler.Collection.Window = &WindowOptions{MaxPolicy: "shrink"}
+	_, err = eventDataHandler.GetWindowConstraints()
+	assert.Error(t, err, "unknown maximum policy \"shrink\"")
+}
+
+func TestGetWindowValidation(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	validation, err := eventDataHandler.GetWindowValidation()
+	assert.NilError(t, err)
+	assert.Equal(t, validation, collector.WindowValidation{FutureSkew: time.Minute, Policy: collector.ValidationFail})
+
+	eventDataHandler.Collection.Validation = &ValidationOptions{Policy: collector.ValidationWarn, FutureSkew: "5m", MaxAge: "24h"}
+	validation, err = eventDataHandler.GetWindowValidation()
+	assert.NilError(t, err)
+	assert.Equal(t, validation, collector.WindowValidation{FutureSkew: 5 * time.Minute, MaxAge: 24 * time.Hour, Policy: collector.ValidationWarn})
+
+	eventDataHandler.Collection.Validation = &ValidationOptions{Policy: "ignore"}
+	_, err = eventDataHandler.GetWindowValidation()
+	assert.Error(t, err, "unknown validation policy \"ignore\"")
+
+	eventDataHandler.Collection.Validation = &ValidationOptions{MaxAge: "-1h"}
+	_, err = eventDataHandler.GetWindowValidation()
+	assert.Error(t, err, "invalid maximum age \"-1h\": expected non-negative duration")
+}
+
+func TestGetModes(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	startMode, err := eventDataHandler.GetEvaluationStartMode()
+	assert.NilError(t, err)
+	assert.Equal(t, startMode, ModeEvent)
+
+	endMode, err := eventDataHandler.GetEvaluationEndMode()
+	assert.NilError(t, err)
+	assert.Equal(t, endMode, ModeEvent)
+
+	duration, err := eventDataHandler.GetEvaluationDuration()
+	assert.NilError(t, err)
+	assert.Equal(t, duration, time.Duration(0))
+
+	eventDataHandler.Collection.EvaluationStartMode = ModeDuration
+	_, err = eventDataHandler.GetEvaluationStartMode()
+	assert.Error(t, err, "evaluation start mode \"duration\" requires an evaluation duration")
+
+	eventDataHandler.Collection.EvaluationDuration = "2h"
+	eventDataHandler.Collection.EvaluationEndMode = ModeTriggered
+	startMode, err = eventDataHandler.GetEvaluationStartMode()
+	assert.NilError(t, err)
+	assert.Equal(t, startMode, ModeDuration)
+
+	endMode, err = eventDataHandler.GetEvaluationEndMode()
+	assert.NilError(t, err)
+	assert.Equal(t, endMode, ModeTriggered)
+
+	duration, err = eventDataHandler.GetEvaluationDuration()
+	assert.NilError(t, err)
+	assert.Equal(t, duration, 2*time.Hour)
+
+	eventDataHandler.Collection.EvaluationStartMode = ModeNow
+	_, err = eventDataHandler.GetEvaluationStartMode()
+	assert.Error(t, err, "invalid evaluation start mode \"now\": expected event or duration")
+
+	eventDataHandler.Collection.EvaluationEndMode = "later"
+	_, err = eventDataHandler.GetEvaluationEndMode()
+	assert.Error(t, err, "invalid evaluation end mode \"later\": expected event, now or triggered")
+
+	eventDataHandler.Collection.EvaluationDuration = "0s"
+	_, err = eventDataHandler.GetEvaluationDuration()
+	assert.Error(t, err, "invalid evaluation duration \"0s\": expected positive duration")
+}
+
+func TestGetTimestampSources(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incomingEvent.DataEncoded = []byte(`{
+		"collection": {
+			"evaluationStartTimestamp": {"source": "field", "path": "test.start"},
+			"evaluationEndTimestamp": "cloudevent"
+		}
+	}`)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	startSource, err := eventDataHandler.GetEvaluationStartTimestampSource()
+	assert.NilError(t, err)
+	assert.Equal(t, startSource, collector.TimestampSource{Kind: collector.TimestampField, Path: "test.start"})
+
+	endSource, err := eventDataHandler.GetEvaluationEndTimestampSource()
+	assert.NilError(t, err)
+	assert.Equal(t, endSource, collector.TimestampSource{Kind: collector.TimestampCloudEvent})
+
+	eventDataHandler.Collection.EvaluationStartTimestamp = nil
+	startSource, err = eventDataHandler.GetEvaluationStartTimestampSource()
+	assert.NilError(t, err)
+	assert.Equal(t, startSource, collector.TimestampSource{Kind: collector.TimestampCloudEvent})
+
+	eventDataHandler.Collection.EvaluationStartTimestamp = &TimestampSource{Source: collector.TimestampField}
+	_, err = eventDataHandler.GetEvaluationStartTimestampSource()
+	assert.Error(t, err, "invalid timestamp source \"field\": path is required")
+
+	eventDataHandler.Collection.EvaluationEndTimestamp = &TimestampSource{Source: "clock"}
+	_, err = eventDataHandler.GetEvaluationEndTimestampSource()
+	assert.Error(t, err, "unknown timestamp source \"clock\"")
+
+	eventDataHandler.Collection.EvaluationEndTimestamp = &TimestampSource{Source: "datastore"}
+	_, err = eventDataHandler.GetEvaluationEndTimestampSource()
+	assert.Error(t, err, "unknown timestamp source \"datastore\"")
+}
+
+func TestGetTrims(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incomingEvent.DataEncoded = []byte(`{
+		"collection": {
+			"trim": {
+				"warmUp": "5m",
+				"coolDown": {"eventType": "sh.keptn.event.test.status.changed", "label": "phase", "value": "rampDown"}
+			}
+		}
+	}`)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	warmUpTrim, err := eventDataHandler.GetWarmUpTrim()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, warmUpTrim, PhaseTrim{Duration: 5 * time.Minute})
+
+	coolDownTrim, err := eventDataHandler.GetCoolDownTrim()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, coolDownTrim, PhaseTrim{MarkerTypes: EventTypes{"sh.keptn.event.test.status.changed"}, Label: "phase", Value: "rampDown"})
+
+	eventDataHandler.Collection.Trim = &TrimOptions{WarmUp: &Trim{Duration: "-5m"}}
+	_, err = eventDataHandler.GetWarmUpTrim()
+	assert.Error(t, err, "invalid warm-up \"-5m\": expected non-negative duration")
+
+	eventDataHandler.Collection.Trim = &TrimOptions{CoolDown: &Trim{Label: "phase"}}
+	_, err = eventDataHandler.GetCoolDownTrim()
+	assert.Error(t, err, "invalid cool-down: marker event type is required")
+
+	eventDataHandler.Collection.Trim = &TrimOptions{CoolDown: &Trim{EventType: EventTypes{"sh.keptn.event.test.status.changed"}, Value: "rampDown"}}
+	_, err = eventDataHandler.GetCoolDownTrim()
+	assert.Error(t, err, "invalid cool-down: label is required for value \"rampDown\"")
+
+	eventDataHandler.Collection.Trim = nil
+	warmUpTrim, err = eventDataHandler.GetWarmUpTrim()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, warmUpTrim, PhaseTrim{})
+}
+
+func TestGetWindowSplit(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	split, err := eventDataHandler.GetWindowSplit()
+	assert.NilError(t, err)
+	assert.Equal(t, split, collector.WindowSplit{})
+
+	eventDataHandler.Collection.Split = &SplitOptions{Count: 4}
+	split, err = eventDataHandler.GetWindowSplit()
+	assert.NilError(t, err)
+	assert.Equal(t, split, collector.WindowSplit{Count: 4})
+
+	eventDataHandler.Collection.Split = &SplitOptions{Duration: "15m"}
+	split, err = eventDataHandler.GetWindowSplit()
+	assert.NilError(t, err)
+	assert.Equal(t, split, collector.WindowSplit{Duration: 15 * time.Minute})
+
+	eventDataHandler.Collection.Split = &SplitOptions{Count: 4, Duration: "15m"}
+	_, err = eventDataHandler.GetWindowSplit()
+	assert.Error(t, err, "invalid split: count and duration are mutually exclusive")
+
+	eventDataHandler.Collection.Split = &SplitOptions{Duration: "0s"}
+	_, err = eventDataHandler.GetWindowSplit()
+	assert.Error(t, err, "invalid split duration \"0s\": expected positive duration")
+
+	eventDataHandler.Collection.Split = &SplitOptions{Count: 101}
+	_, err = eventDataHandler.GetWindowSplit()
+	assert.Error(t, err, "invalid split count 101: expected 1 to 100")
+}
+
+func TestGetNamedWindows(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	incomingEvent.DataEncoded = []byte(`{
+		"stage": "staging",
+		"collection": {
+			"evaluationStartEventType": "sh.keptn.event.test.started",
+			"windows": [
+				{"name": "smoke", "evaluationStartEventType": "sh.keptn.event.smoke-test.started", "evaluationEndStrategy": "first"},
+				{"name": "load-test", "scope": "context"}
+			]
+		}
+	}`)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	namedCollections, err := eventDataHandler.GetNamedWindows()
+	assert.NilError(t, err)
+	assert.Equal(t, len(namedCollections), 2)
+
+	assert.Equal(t, namedCollections[0].Name, "smoke")
+	assert.DeepEqual(t, namedCollections[0].Data.GetEvaluationStartEventFilter(), EventTypes{"sh.keptn.event.smoke-test.started"})
+	assert.Equal(t, namedCollections[0].Data.GetEventData().Stage, "staging")
+
+	strategy, err := namedCollections[0].Data.GetEvaluationEndStrategy()
+	assert.NilError(t, err)
+	assert.Equal(t, strategy.Name, collector.StrategyFirst)
+
+	assert.Equal(t, namedCollections[1].Name, "load-test")
+	assert.Equal(t, len(namedCollections[1].Data.GetEvaluationStartEventFilter()), 0)
+
+	scope, err := namedCollections[1].Data.GetScope()
+	assert.NilError(t, err)
+	assert.Equal(t, scope, ScopeContext)
+
+	eventDataHandler.Collection.Windows = []NamedWindow{{Name: "load test"}}
+	_, err = eventDataHandler.GetNamedWindows()
+	assert.Error(t, err, "invalid window name \"load test\": expected letters, digits, - or _")
+
+	eventDataHandler.Collection.Windows = []NamedWindow{{Name: "load-test"}, {Name: "LOAD_TEST"}}
+	_, err = eventDataHandler.GetNamedWindows()
+	assert.Error(t, err, "window name \"LOAD_TEST\" collides with window \"load-test\"")
+
+	eventDataHandler.Collection.Windows = []NamedWindow{{Name: "load", CollectionData: CollectionData{Windows: []NamedWindow{{Name: "soak"}}}}}
+	_, err = eventDataHandler.GetNamedWindows()
+	assert.Error(t, err, "window \"load\" must not define windows")
+
+	eventDataHandler.Collection.Windows = []NamedWindow{{Name: "load", CollectionData: CollectionData{Baseline: &BaselineOptions{}}}}
+	_, err = eventDataHandler.GetNamedWindows()
+	assert.Error(t, err, "window \"load\" must not define a baseline")
+}
+
+func TestGetBaselineFilters(t *testing.T) {
+	_, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	lookupTime := time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)
+
+	filters, err := eventDataHandler.GetBaselineFilters(lookupTime)
+	assert.NilError(t, err)
+	assert.Assert(t, filters == nil)
+
+	// Default types are queried one by one, so they can be pushed down to the event source
+	eventDataHandler.Collection.Baseline = &BaselineOptions{}
+	filters, err = eventDataHandler.GetBaselineFilters(lookupTime)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filters, []collector.EventFilter{
+		{
+			Project:  "simplenode-gitlab",
+			Stage:    "staging",
+			Service:  "simplenodeservice",
+			Type:     "sh.keptn.event.collection.finished",
+			FromTime: lookupTime.Add(-defaultBaselineMaxAge),
+		},
+		{
+			Project:  "simplenode-gitlab",
+			Stage:    "staging",
+			Service:  "simplenodeservice",
+			Type:     "sh.keptn.event.evaluation.finished",
+			FromTime: lookupTime.Add(-defaultBaselineMaxAge),
+		},
+	})
+
+	eventDataHandler.Collection.Baseline = &BaselineOptions{EventType: EventTypes{"sh.keptn.event.evaluation.finished"}, Stage: "production", MaxAge: "168h"}
+	filters, err = eventDataHandler.GetBaselineFilters(lookupTime)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filters, []collector.EventFilter{
+		{
+			Project:  "simplenode-gitlab",
+			Stage:    "production",
+			Service:  "simplenodeservice",
+			Type:     "sh.keptn.event.evaluation.finished",
+			FromTime: lookupTime.Add(-168 * time.Hour),
+		},
+	})
+
+	// Patterns are combined into one filter applied client-side
+	eventDataHandler.Collection.Baseline = &BaselineOptions{EventType: EventTypes{"sh.keptn.event.*-test.finished", "sh.keptn.event.evaluation.finished", "sh.keptn.event.collection.*"}}
+	filters, err = eventDataHandler.GetBaselineFilters(lookupTime)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, filters, []collector.EventFilter{
+		{
+			Project:  "simplenode-gitlab",
+			Stage:    "staging",
+			Service:  "simplenodeservice",
+			Type:     "sh.keptn.event.evaluation.finished",
+			FromTime: lookupTime.Add(-defaultBaselineMaxAge),
+		},
+		{
+			Project:     "simplenode-gitlab",
+			Stage:       "staging",
+			Service:     "simplenodeservice",
+			TypePattern: "^(?:sh\\.keptn\\.event\\..*-test\\.finished|sh\\.keptn\\.event\\.collection\\..*)$",
+			FromTime:    lookupTime.Add(-defaultBaselineMaxAge),
+		},
+	})
+
+	eventDataHandler.Collection.Baseline = &BaselineOptions{MaxAge: "a week"}
+	_, err = eventDataHandler.GetBaselineFilters(lookupTime)
+	assert.Error(t, err, "invalid baseline maximum age \"a week\": expected non-negative duration")
+}
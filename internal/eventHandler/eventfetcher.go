@@ -0,0 +1,91 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+const maxConcurrentEventFetches = 4
+
+/**
+ * Fetches the events matching each distinct filter concurrently. At most
+ * maxConcurrentEventFetches requests are in flight at a time, and the first
+ * failing request cancels all remaining ones. Decode warnings of all fetches
+ * are merged, reporting every event only once.
+ */
+func fetchFilteredEvents(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	filters []collector.EventFilter,
+) (map[collector.EventFilter][]*collector.EventEnvelope, []collector.DecodeWarning, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	eventsByFilter := map[collector.EventFilter][]*collector.EventEnvelope{}
+	isRequested := map[collector.EventFilter]bool{}
+	warnings := []collector.DecodeWarning{}
+	isWarned := map[string]bool{}
+
+	var mutex sync.Mutex
+	var firstErr error
+	var wg sync.WaitGroup
+
+	semaphore := make(chan struct{}, maxConcurrentEventFetches)
+
+	for _, filter := range filters {
+		if isRequested[filter] {
+			continue
+		}
+		isRequested[filter] = true
+
+		wg.Add(1)
+		go func(filter collector.EventFilter) {
+			defer wg.Done()
+
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				return
+			}
+
+			events, eventWarnings, err := collectorIface.GetFilteredEvents(ctx, filter)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to fetch events of %s: %w", filter.String(), err)
+					cancel()
+				}
+				return
+			}
+
+			eventsByFilter[filter] = events
+
+			for _, warning := range eventWarnings {
+				if !isWarned[warning.EventId] {
+					isWarned[warning.EventId] = true
+					warnings = append(warnings, warning)
+				}
+			}
+		}(filter)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, nil, firstErr
+	}
+
+	// The parent context may have been cancelled before all fetches were started
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	return eventsByFilter, warnings, nil
+}
@@ -0,0 +1,56 @@
+package eventHandler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+	"gotest.tools/assert"
+)
+
+func TestFetchFilteredEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockCollectorIface(ctrl)
+
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}).Return([]*collector.EventEnvelope{
+		{Event: cloudevents.NewEvent()},
+	}, []collector.DecodeWarning{}, nil).Times(1)
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}).Return([]*collector.EventEnvelope{
+		{Event: cloudevents.NewEvent()},
+		{Event: cloudevents.NewEvent()},
+	}, []collector.DecodeWarning{}, nil).Times(1)
+
+	eventsByFilter, _, err := fetchFilteredEvents(context.Background(), m, []collector.EventFilter{
+		{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"},
+		{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"},
+		{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(eventsByFilter), 2)
+	assert.Equal(t, len(eventsByFilter[collector.EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}]), 1)
+	assert.Equal(t, len(eventsByFilter[collector.EventFilter{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}]), 2)
+}
+
+func TestFetchFilteredEventsCancelsOnError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := NewMockCollectorIface(ctrl)
+
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}).Return(nil, nil, errors.New("unavailable")).Times(1)
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"}).DoAndReturn(func(ctx context.Context, filter collector.EventFilter) ([]*collector.EventEnvelope, []collector.DecodeWarning, error) {
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}).Times(1)
+
+	_, _, err := fetchFilteredEvents(context.Background(), m, []collector.EventFilter{
+		{KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"},
+		{KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"},
+	})
+	assert.Error(t, err, "failed to fetch events of context aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa: unavailable")
+}
@@ -3,6 +3,7 @@
 package eventHandler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,8 +11,10 @@ import (
 	time "time"
 
 	gomock "github.com/golang/mock/gomock"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
 	"github.com/keptn/go-utils/pkg/lib/v0_2_0/fake"
 	"gotest.tools/assert"
+	"gotest.tools/assert/cmp"
 
 	keptn "github.com/keptn/go-utils/pkg/lib/keptn"
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
@@ -77,30 +80,37 @@ func TestSyntheticCloudEventHandler(t *testing.T) {
 	}
 	mockSyntheticTestFinishedEvent.SetType("mock.synthetic.finished.event")
 
-	m.EXPECT().GetEvents(gomock.Any()).Return([]cloudevents.Event{
-		mockStartedEvent,
-		mockSyntheticTestFinishedEvent,
-		mockFinishedEvent,
-	}, nil).AnyTimes()
-	m.EXPECT().CollectExecutionIds(gomock.Any()).Return([]string{"executionId", "executionId", "executionId"}, nil)
-	m.EXPECT().CollectBatchIds(gomock.Any()).Return([]string{"batchId"}, nil)
-	m.EXPECT().ParseEvents(gomock.Any(), "mock.collection.start.event", "").Return([]cloudevents.Event{
-		mockStartedEvent,
-	})
-	m.EXPECT().ParseEvents(gomock.Any(), "mock.collection.end.event", "").Return([]cloudevents.Event{
-		mockFinishedEvent,
-	})
-	m.EXPECT().ParseEvents(gomock.Any(), "mock.synthetic.finished.event", "").Return([]cloudevents.Event{
-		mockSyntheticTestFinishedEvent,
-	})
+	m.EXPECT().CollectExecutionIds(gomock.Any()).Return([]string{"executionId", "executionId", "executionId"})
+	m.EXPECT().CollectBatchIds(gomock.Any()).Return([]string{"batchId"})
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{
+		KeptnContext: "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		Type:         "mock.collection.start.event",
+	}).Return([]*collector.EventEnvelope{
+		{Event: mockStartedEvent},
+	}, []collector.DecodeWarning{}, nil)
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{
+		KeptnContext: "zzzzzzzz-zzzz-zzzz-zzzz-zzzzzzzzzzzz",
+		Type:         "mock.collection.end.event",
+	}).Return([]*collector.EventEnvelope{
+		{Event: mockFinishedEvent},
+	}, []collector.DecodeWarning{}, nil)
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{
+		KeptnContext: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+		Type:         "mock.synthetic.finished.event",
+	}).Return([]*collector.EventEnvelope{
+		{Event: mockSyntheticTestFinishedEvent},
+	}, []collector.DecodeWarning{}, nil)
+
+	m.EXPECT().SelectEvent(gomock.Any(), collector.SelectionStrategy{Name: collector.StrategyFirst}).Return(&collector.EventEnvelope{Event: mockStartedEvent}, nil)
+	m.EXPECT().SelectEvent(gomock.Any(), collector.SelectionStrategy{Name: collector.StrategyLast}).Return(&collector.EventEnvelope{Event: mockFinishedEvent}, nil)
 
 	timestampA, _ := time.Parse(time.RFC3339, "2022-04-07T12:04:28Z")
-	m.EXPECT().CollectEarliestTime(gomock.Any(), gomock.Any()).Return(timestampA, nil)
+	m.EXPECT().CollectEarliestTime(gomock.Any(), false).Return(timestampA, nil)
 
 	timestampB, _ := time.Parse(time.RFC3339, "2022-04-07T12:05:29Z")
-	m.EXPECT().CollectLatestTime(gomock.Any(), gomock.Any()).Return(timestampB, nil)
+	m.EXPECT().CollectLatestTime(gomock.Any(), false).Return(timestampB, nil)
 
-	err = CollectionCloudEventHandler(myKeptn, *incomingEvent, "serviceName", m, eventDataHandlerIface)
+	err = CollectionCloudEventHandler(context.Background(), myKeptn, *incomingEvent, "serviceName", m, eventDataHandlerIface)
 	assert.NilError(t, err)
 
 	assert.Equal(t, len(myKeptn.EventSender.(*fake.EventSender).SentEvents), 2)
@@ -110,8 +120,17 @@ func TestSyntheticCloudEventHandler(t *testing.T) {
 	finishedEventData := CollectionSuccessfulEventData{}
 	myKeptn.EventSender.(*fake.EventSender).SentEvents[1].DataAs(&finishedEventData)
 
-	assert.Equal(t, finishedEventData.Evaluation.Start, timestampA.Format(time.RFC3339))
-	assert.Equal(t, finishedEventData.Evaluation.End, timestampB.Format(time.RFC3339))
+	assert.Equal(t, finishedEventData.Evaluation.Start, "2022-04-07T12:04:00Z")
+	assert.Equal(t, finishedEventData.Evaluation.End, "2022-04-07T12:06:00Z")
+	assert.Equal(t, finishedEventData.Evaluation.Timeframe, "2m")
+	assert.Equal(t, finishedEventData.UnroundedEvaluation.Start, timestampA.Format(time.RFC3339Nano))
+	assert.Equal(t, finishedEventData.UnroundedEvaluation.End, timestampB.Format(time.RFC3339Nano))
+	assert.Equal(t, finishedEventData.Selection.Start.Rounding, "floor 1m0s")
+	assert.Equal(t, finishedEventData.Selection.End.Rounding, "ceil 1m0s")
+	assert.Equal(t, finishedEventData.Selection.Start.Strategy, collector.StrategyFirst)
+	assert.Equal(t, finishedEventData.Selection.Start.EventType, "mock.collection.start.event")
+	assert.Equal(t, finishedEventData.Selection.End.Strategy, collector.StrategyLast)
+	assert.Equal(t, finishedEventData.Selection.End.EventType, "mock.collection.end.event")
 
 	// Test empty event
 	m = NewMockCollectorIface(ctrl)
@@ -127,30 +146,1119 @@ func TestSyntheticCloudEventHandler(t *testing.T) {
 		t.Errorf("Error getting keptn event data")
 	}
 
-	m.EXPECT().GetEvents(gomock.Any()).Return([]cloudevents.Event{
-		mockStartedEvent,
-		mockSyntheticTestFinishedEvent,
-		mockFinishedEvent,
-	}, nil).AnyTimes()
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Stage:        "staging",
+	}).Return([]*collector.EventEnvelope{
+		{Event: mockStartedEvent},
+		{Event: mockFinishedEvent},
+	}, []collector.DecodeWarning{}, nil).Times(1)
 
-	m.EXPECT().ParseEvents(gomock.Any(), "", "").Return([]cloudevents.Event{
-		mockStartedEvent,
-		mockFinishedEvent,
-	}).Times(2)
+	m.EXPECT().GetFilteredEvents(gomock.Any(), collector.EventFilter{
+		KeptnContext: "0dc1538a-2550-49b5-8319-30d57a83519f",
+		Stage:        "staging",
+		Type:         "sh.keptn.event.test.finished",
+	}).Return([]*collector.EventEnvelope{
+		{Event: mockSyntheticTestFinishedEvent},
+	}, []collector.DecodeWarning{}, nil).Times(1)
 
-	m.EXPECT().ParseEvents(gomock.Any(), "sh.keptn.event.test.finished", "").Return([]cloudevents.Event{
-		mockSyntheticTestFinishedEvent,
-	}).Times(1)
+	m.EXPECT().SelectEvent(gomock.Any(), collector.SelectionStrategy{Name: collector.StrategyFirst}).Return(&collector.EventEnvelope{Event: mockStartedEvent}, nil)
+	m.EXPECT().SelectEvent(gomock.Any(), collector.SelectionStrategy{Name: collector.StrategyLast}).Return(&collector.EventEnvelope{Event: mockFinishedEvent}, nil)
 
 	timestampA, _ = time.Parse(time.RFC3339, "2022-04-07T12:04:28Z")
-	m.EXPECT().CollectEarliestTime(gomock.Any(), gomock.Any()).Return(timestampA, nil)
+	m.EXPECT().CollectEarliestTime(gomock.Any(), false).Return(timestampA, nil)
 
 	timestampB, _ = time.Parse(time.RFC3339, "2022-04-07T12:05:29Z")
-	m.EXPECT().CollectLatestTime(gomock.Any(), gomock.Any()).Return(timestampB, nil)
+	m.EXPECT().CollectLatestTime(gomock.Any(), false).Return(timestampB, nil)
+
+	m.EXPECT().CollectExecutionIds(gomock.Any()).Return([]string{"executionId", "executionId", "executionId"})
+	m.EXPECT().CollectBatchIds(gomock.Any()).Return([]string{"batchId"})
+
+	err = CollectionCloudEventHandler(context.Background(), myKeptn, *incomingEvent, "serviceName", m, eventDataHandlerIface)
+	assert.NilError(t, err)
+}
+
+func TestCollectionCloudEventHandlerWithFileEventSource(t *testing.T) {
+	eventSource, err := collector.NewFileEventSource("../../test-events")
+	assert.NilError(t, err)
+
+	myKeptn, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	eventDataHandlerIface, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
+
+	err = CollectionCloudEventHandler(context.Background(), myKeptn, *incomingEvent, "serviceName", collector.NewCollector(eventSource), eventDataHandlerIface)
+	assert.NilError(t, err)
+
+	sentEvents := myKeptn.EventSender.(*fake.EventSender).SentEvents
+	assert.Equal(t, len(sentEvents), 2)
+
+	finishedEventData := CollectionSuccessfulEventData{}
+	err = sentEvents[1].DataAs(&finishedEventData)
+	assert.NilError(t, err)
+
+	assert.Assert(t, finishedEventData.Status != keptnv2.StatusErrored, finishedEventData.Message)
+	assert.Equal(t, finishedEventData.Evaluation.Start, "2022-04-05T09:49:00Z")
+	assert.Equal(t, finishedEventData.Evaluation.End, "2022-04-07T12:06:00Z")
+}
+
+// Context of the incoming event of collection.triggered-empty.json
+const testKeptnContext = "0dc1538a-2550-49b5-8319-30d57a83519f"
+
+/**
+ * Creates an event of the context with the given data, e.g. `{"stage": "staging"}`.
+ */
+func newTestEvent(id string, keptnContext string, eventType string, timestamp time.Time, data string) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID(id)
+	event.SetType(eventType)
+	event.SetTime(timestamp)
+	event.SetExtension("shkeptncontext", keptnContext)
+	event.DataEncoded = []byte(data)
 
-	m.EXPECT().CollectExecutionIds(gomock.Any()).Return([]string{"executionId", "executionId", "executionId"}, nil)
-	m.EXPECT().CollectBatchIds(gomock.Any()).Return([]string{"batchId"}, nil)
+	return event
+}
+
+/**
+ * Handles the event of collection.triggered-empty.json with the given data against the events and
+ * returns the data of the sent sh.keptn.event.collection.finished event.
+ */
+func runCollection(t *testing.T, data string, events []cloudevents.Event) CollectionSuccessfulEventData {
+	return runCollectionAt(t, time.Time{}, data, events)
+}
+
+/**
+ * Like runCollection, but with the collection triggered at the given time instead of the time of
+ * collection.triggered-empty.json.
+ */
+func runCollectionAt(t *testing.T, triggeredTime time.Time, data string, events []cloudevents.Event) CollectionSuccessfulEventData {
+	t.Helper()
+
+	myKeptn, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+	assert.NilError(t, err)
+
+	if !triggeredTime.IsZero() {
+		incomingEvent.SetTime(triggeredTime)
+	}
+	incomingEvent.DataEncoded = []byte(data)
+
+	eventDataHandler, err := NewEventDataHandler(*incomingEvent)
+	assert.NilError(t, err)
 
-	err = CollectionCloudEventHandler(myKeptn, *incomingEvent, "serviceName", m, eventDataHandlerIface)
+	err = CollectionCloudEventHandler(context.Background(), myKeptn, *incomingEvent, "serviceName", collector.NewCollector(collector.NewMemoryEventSource(events...)), eventDataHandler)
 	assert.NilError(t, err)
+
+	sentEvents := myKeptn.EventSender.(*fake.EventSender).SentEvents
+	assert.Equal(t, len(sentEvents), 2)
+
+	finishedEventData := CollectionSuccessfulEventData{}
+	err = sentEvents[1].DataAs(&finishedEventData)
+	assert.NilError(t, err)
+
+	return finishedEventData
+}
+
+/**
+ * Asserts that the collection failed with the expected error or, if none is expected, didn't fail.
+ * Returns whether the collection succeeded.
+ */
+func assertCollectionStatus(t *testing.T, finishedEventData CollectionSuccessfulEventData, expectedError string) bool {
+	t.Helper()
+
+	if expectedError != "" {
+		assert.Equal(t, finishedEventData.Status, keptnv2.StatusErrored)
+		assert.Equal(t, finishedEventData.Message, expectedError)
+		return false
+	}
+
+	assert.Assert(t, finishedEventData.Status != keptnv2.StatusErrored, finishedEventData.Message)
+	return true
+}
+
+func TestCollectionCloudEventHandlerReportsDecodeWarnings(t *testing.T) {
+	malformedEvent := newTestEvent("malformed", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 12, 1, 0, 0, time.UTC), `{"stage": `)
+	triggeredEvent := newTestEvent("triggered", testKeptnContext, "sh.keptn.event.collection.triggered", time.Date(2022, 4, 7, 12, 5, 28, 0, time.UTC), `{"stage": "staging"}`)
+
+	// The stage of malformed events is unknown, so only the context scope selects them
+	finishedEventData := runCollection(t, `{"stage": "staging", "collection": {"scope": "context"}}`, []cloudevents.Event{triggeredEvent, malformedEvent})
+
+	assertCollectionStatus(t, finishedEventData, "")
+	assert.Equal(t, finishedEventData.Evaluation.Start, "2022-04-07T12:01:00Z")
+	assert.Equal(t, len(finishedEventData.Warnings), 1)
+	assert.Assert(t, cmp.Contains(finishedEventData.Warnings[0], "event malformed"))
+}
+
+func TestCollectionCloudEventHandlerScopes(t *testing.T) {
+	dev := `{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "dev"}`
+	staging := `{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "staging"}`
+
+	events := []cloudevents.Event{
+		newTestEvent("deployed", testKeptnContext, "sh.keptn.event.deployment.finished", time.Date(2022, 4, 7, 9, 0, 0, 0, time.UTC), dev),
+		newTestEvent("delivery", testKeptnContext, "sh.keptn.event.staging.delivery.triggered", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), staging),
+		newTestEvent("test", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 5, 0, 0, time.UTC), staging),
+		newTestEvent("evaluation", testKeptnContext, "sh.keptn.event.staging.evaluation.triggered", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC), staging),
+		newTestEvent("collection", testKeptnContext, "sh.keptn.event.collection.triggered", time.Date(2022, 4, 7, 12, 5, 28, 0, time.UTC), staging),
+	}
+
+	tests := []struct {
+		name          string
+		scope         string
+		expectedStart string
+	}{
+		{name: "context", scope: ScopeContext, expectedStart: "2022-04-07T09:00:00Z"},
+		{name: "default", scope: "", expectedStart: "2022-04-07T10:00:00Z"},
+		{name: "stage", scope: ScopeStage, expectedStart: "2022-04-07T10:00:00Z"},
+		{name: "sequence", scope: ScopeSequence, expectedStart: "2022-04-07T11:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "staging", "collection": {"scope": "`+test.scope+`"}}`, events)
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, "2022-04-07T12:06:00Z")
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerSelectsExecution(t *testing.T) {
+	newEvent := func(id string, eventType string, triggeredId string, timestamp time.Time) cloudevents.Event {
+		event := newTestEvent(id, testKeptnContext, eventType, timestamp, `{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "staging"}`)
+		if triggeredId != "" {
+			event.SetExtension("triggeredid", triggeredId)
+		}
+
+		return event
+	}
+
+	events := []cloudevents.Event{
+		newEvent("run-1", "sh.keptn.event.test.triggered", "", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC)),
+		newEvent("run-1-started", "sh.keptn.event.test.started", "run-1", time.Date(2022, 4, 7, 10, 1, 10, 0, time.UTC)),
+		newEvent("run-1-finished", "sh.keptn.event.test.finished", "run-1", time.Date(2022, 4, 7, 10, 30, 10, 0, time.UTC)),
+		newEvent("run-2", "sh.keptn.event.test.triggered", "", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC)),
+		newEvent("run-2-started", "sh.keptn.event.test.started", "run-2", time.Date(2022, 4, 7, 11, 1, 10, 0, time.UTC)),
+		newEvent("run-2-finished", "sh.keptn.event.test.finished", "run-2", time.Date(2022, 4, 7, 11, 30, 10, 0, time.UTC)),
+	}
+
+	// A retry that was started but didn't finish yet
+	unfinishedRetry := []cloudevents.Event{
+		newEvent("run-3", "sh.keptn.event.test.triggered", "", time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC)),
+		newEvent("run-3-started", "sh.keptn.event.test.started", "run-3", time.Date(2022, 4, 7, 12, 1, 10, 0, time.UTC)),
+	}
+
+	tests := []struct {
+		name            string
+		execution       string
+		unfinishedRetry bool
+		expectedStart   string
+		expectedEnd     string
+		expectedError   string
+	}{
+		{name: "all", execution: "", expectedStart: "2022-04-07T10:01:00Z", expectedEnd: "2022-04-07T11:31:00Z"},
+		{name: "first", execution: collector.ExecutionFirst, expectedStart: "2022-04-07T10:01:00Z", expectedEnd: "2022-04-07T10:31:00Z"},
+		{name: "latest", execution: collector.ExecutionLatest, expectedStart: "2022-04-07T11:01:00Z", expectedEnd: "2022-04-07T11:31:00Z"},
+		{name: "triggered event ID", execution: "run-1", expectedStart: "2022-04-07T10:01:00Z", expectedEnd: "2022-04-07T10:31:00Z"},
+		{name: "first with unfinished retry", execution: collector.ExecutionFirst, unfinishedRetry: true, expectedStart: "2022-04-07T10:01:00Z", expectedEnd: "2022-04-07T10:31:00Z"},
+		{
+			name:            "latest with unfinished retry",
+			execution:       collector.ExecutionLatest,
+			unfinishedRetry: true,
+			expectedError:   "ABORTING. Failed to select execution for context 0dc1538a-2550-49b5-8319-30d57a83519f, stage \"staging\", type \"sh.keptn.event.test.started\" and context 0dc1538a-2550-49b5-8319-30d57a83519f, stage \"staging\", type \"sh.keptn.event.test.finished\": execution \"latest\" holds no end events",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testEvents := events
+			if test.unfinishedRetry {
+				testEvents = append(append([]cloudevents.Event{}, events...), unfinishedRetry...)
+			}
+
+			finishedEventData := runCollection(t, `{"project": "simplenode-gitlab", "service": "simplenodeservice", "stage": "staging", "collection": {
+				"evaluationStartEventType": "sh.keptn.event.test.started",
+				"evaluationStartExecution": "`+test.execution+`",
+				"evaluationEndEventType": "sh.keptn.event.test.finished",
+				"evaluationEndExecution": "`+test.execution+`"
+			}}`, testEvents)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerSelectsByExpression(t *testing.T) {
+	newEvent := func(id string, source string, result string, timestamp time.Time) cloudevents.Event {
+		event := newTestEvent(id, testKeptnContext, "sh.keptn.event.test.finished", timestamp, `{"stage": "staging", "result": "`+result+`"}`)
+		event.SetSource(source)
+
+		return event
+	}
+
+	events := []cloudevents.Event{
+		newEvent("failed", "jmeter-service", "fail", time.Date(2022, 4, 7, 10, 0, 10, 0, time.UTC)),
+		newEvent("passed", "jmeter-service", "pass", time.Date(2022, 4, 7, 11, 0, 10, 0, time.UTC)),
+		newEvent("other", "locust-service", "pass", time.Date(2022, 4, 7, 12, 0, 10, 0, time.UTC)),
+	}
+
+	tests := []struct {
+		name          string
+		collection    string
+		expectedStart string
+		expectedEnd   string
+		expectedError string
+	}{
+		{
+			name:          "selected",
+			collection:    `{"evaluationStartExpression": "source == \"jmeter-service\" && data.result == \"pass\"", "evaluationEndExpression": "source.startsWith(\"jmeter\")"}`,
+			expectedStart: "2022-04-07T11:00:00Z",
+			expectedEnd:   "2022-04-07T11:01:00Z",
+		},
+		{
+			// Compile errors fail the collection before events are fetched
+			name:          "invalid",
+			collection:    `{"syntheticTestFinishedExpression": "data.result = \"pass\""}`,
+			expectedError: "invalid synthetic test finished expression: Syntax error: token recognition error at: '= ' at position 12; Syntax error: extraneous input '\"pass\"' expecting <EOF> at position 14",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{"stage": "staging", "collection": `+test.collection+`}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerStrategies(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("started-1", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 9, 0, 10, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("deployed", testKeptnContext, "sh.keptn.event.deployment.finished", time.Date(2022, 4, 7, 9, 30, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("started-2", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 0, 10, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("started-3", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 11, 0, 10, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("started-4", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 13, 0, 10, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name            string
+		startStrategy   string
+		endStrategy     string
+		expectedStartId string
+		expectedEndId   string
+	}{
+		{name: "names", startStrategy: `"last"`, endStrategy: `"first"`, expectedStartId: "started-4", expectedEndId: "started-1"},
+		{name: "nth", startStrategy: `{"name": "nth", "n": 2}`, endStrategy: `{"name": "nth", "n": -2}`, expectedStartId: "started-2", expectedEndId: "started-3"},
+		{name: "anchored", startStrategy: `{"name": "after", "anchor": "sh.keptn.event.deployment.finished"}`, endStrategy: `"beforeTriggered"`, expectedStartId: "started-2", expectedEndId: "started-3"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{
+				"stage": "staging",
+				"collection": {
+					"evaluationStartEventType": "sh.keptn.event.test.started",
+					"evaluationStartStrategy": `+test.startStrategy+`,
+					"evaluationEndEventType": "sh.keptn.event.test.started",
+					"evaluationEndStrategy": `+test.endStrategy+`,
+					"validation": {"policy": "warn"}
+				}
+			}`, events)
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Selection.Start.EventId, test.expectedStartId)
+			assert.Equal(t, finishedEventData.Selection.End.EventId, test.expectedEndId)
+		})
+	}
+}
+
+func TestInvalidCollectionCloudEventHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		collection    string
+		expectedError string
+	}{
+		{name: "strategy", collection: `{"evaluationStartStrategy": 1}`, expectedError: "invalid collection data: expected strategy name or object"},
+		{name: "rounding", collection: `{"evaluationEndRounding": []}`, expectedError: "invalid collection data: expected rounding mode or object"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			myKeptn, incomingEvent, err := initializeTestObjects("../../test-events/collection.triggered-empty.json")
+			assert.NilError(t, err)
+
+			incomingEvent.DataEncoded = []byte(`{"project": "simplenode-gitlab", "stage": "staging", "service": "simplenodeservice", "collection": ` + test.collection + `}`)
+
+			_, err = NewEventDataHandler(*incomingEvent)
+			assert.Error(t, err, test.expectedError)
+
+			err = InvalidCollectionCloudEventHandler(myKeptn, *incomingEvent, "serviceName", err)
+			assert.NilError(t, err)
+
+			sentEvents := myKeptn.EventSender.(*fake.EventSender).SentEvents
+			assert.Equal(t, len(sentEvents), 2)
+
+			finishedEventData := keptnv2.EventData{}
+			err = sentEvents[1].DataAs(&finishedEventData)
+			assert.NilError(t, err)
+
+			assert.Equal(t, finishedEventData.Status, keptnv2.StatusErrored)
+			assert.Equal(t, finishedEventData.Message, test.expectedError)
+			assert.Equal(t, finishedEventData.Stage, "staging")
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerRounding(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("start", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 2, 40, 500000000, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("end", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 11, 5, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name          string
+		startRounding string
+		endRounding   string
+		expectedStart string
+		expectedEnd   string
+	}{
+		{name: "default", startRounding: `null`, endRounding: `null`, expectedStart: "2022-04-07T10:02:00Z", expectedEnd: "2022-04-07T11:05:00Z"},
+		{name: "none", startRounding: `"none"`, endRounding: `"none"`, expectedStart: "2022-04-07T10:02:40Z", expectedEnd: "2022-04-07T11:05:00Z"},
+		{name: "nearest and ceil", startRounding: `{"mode": "nearest", "granularity": "5m"}`, endRounding: `{"mode": "ceil", "granularity": "30s"}`, expectedStart: "2022-04-07T10:05:00Z", expectedEnd: "2022-04-07T11:05:00Z"},
+		{name: "ceil and floor", startRounding: `{"mode": "ceil", "granularity": "1s"}`, endRounding: `{"mode": "floor", "granularity": "1h"}`, expectedStart: "2022-04-07T10:02:41Z", expectedEnd: "2022-04-07T11:00:00Z"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{
+				"stage": "staging",
+				"collection": {
+					"evaluationStartRounding": `+test.startRounding+`,
+					"evaluationEndEventType": "sh.keptn.event.test.started",
+					"evaluationEndRounding": `+test.endRounding+`
+				}
+			}`, events)
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+			assert.Equal(t, finishedEventData.UnroundedEvaluation.Start, "2022-04-07T10:02:40.5Z")
+			assert.Equal(t, finishedEventData.UnroundedEvaluation.End, "2022-04-07T11:05:00Z")
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerWindowConstraints(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("start", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 5, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("end", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name                string
+		window              string
+		expectedStart       string
+		expectedEnd         string
+		expectedAdjustments []string
+		expectedError       string
+	}{
+		{
+			name:                "padded and trimmed",
+			window:              `{"prePadding": "5m", "maxDuration": "1h", "maxPolicy": "trimStart"}`,
+			expectedStart:       "2022-04-07T09:00:00Z",
+			expectedEnd:         "2022-04-07T10:00:00Z",
+			expectedAdjustments: []string{"padded start by 5m0s", "trimmed start by 47h5m0s to maximum of 1h0m0s"},
+		},
+		{
+			name:          "exceeds maximum",
+			window:        `{"maxDuration": "24h"}`,
+			expectedError: "ABORTING. Failed to adjust evaluation window: window of 48h0m0s exceeds maximum of 24h0m0s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{"stage": "staging", "collection": {"window": `+test.window+`}}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+			assert.DeepEqual(t, finishedEventData.Adjustments, test.expectedAdjustments)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerWindowValidation(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("earlier", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("later", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name             string
+		validation       string
+		window           string
+		expectedError    string
+		expectedWarnings []string
+	}{
+		{
+			name:          "fail",
+			validation:    `null`,
+			expectedError: "ABORTING. Invalid evaluation window: window end 2022-04-07T10:00:00Z is before start 2022-04-07T11:00:00Z",
+		},
+		{
+			name:             "warn",
+			validation:       `{"policy": "warn"}`,
+			expectedWarnings: []string{"window end 2022-04-07T10:00:00Z is before start 2022-04-07T11:00:00Z"},
+		},
+		{
+			// Growing the window to its minimum duration must not hide that it is inverted
+			name:          "fail before constraints",
+			validation:    `null`,
+			window:        `{"minDuration": "2h"}`,
+			expectedError: "ABORTING. Invalid evaluation window: window end 2022-04-07T10:00:00Z is before start 2022-04-07T11:00:00Z",
+		},
+		{
+			name:          "warn before constraints",
+			validation:    `{"policy": "warn"}`,
+			window:        `{"minDuration": "2h"}`,
+			expectedError: "ABORTING. Failed to adjust evaluation window: window end 2022-04-07T10:00:00Z is before start 2022-04-07T11:00:00Z, can't grow it to minimum of 2h0m0s",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.window == "" {
+				test.window = `null`
+			}
+
+			finishedEventData := runCollection(t, `{
+				"stage": "staging",
+				"collection": {
+					"evaluationStartStrategy": "last",
+					"evaluationStartRounding": "none",
+					"evaluationEndStrategy": "first",
+					"evaluationEndRounding": "none",
+					"validation": `+test.validation+`,
+					"window": `+test.window+`
+				}
+			}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.DeepEqual(t, finishedEventData.Warnings, test.expectedWarnings)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerRelativeModes(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("started", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 2, 30, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("finished", testKeptnContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 7, 10, 30, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	triggeredTime := time.Date(2022, 4, 7, 11, 0, 20, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		collection        string
+		expectedStart     string
+		expectedEnd       string
+		expectedTimeframe string
+		expectedStartMode string
+		expectedEndMode   string
+		expectedWarnings  []string
+	}{
+		{
+			name:              "events",
+			collection:        `{"evaluationStartEventType": "sh.keptn.event.test.started", "evaluationEndEventType": "sh.keptn.event.test.finished"}`,
+			expectedStart:     "2022-04-07T10:02:00Z",
+			expectedEnd:       "2022-04-07T10:30:00Z",
+			expectedTimeframe: "28m",
+			expectedStartMode: "first",
+			expectedEndMode:   "last",
+		},
+		{
+			name:              "end triggered",
+			collection:        `{"evaluationStartEventType": "sh.keptn.event.test.started", "evaluationEndMode": "triggered"}`,
+			expectedStart:     "2022-04-07T10:02:00Z",
+			expectedEnd:       "2022-04-07T11:01:00Z",
+			expectedTimeframe: "59m",
+			expectedStartMode: "first",
+			expectedEndMode:   "triggered",
+		},
+		{
+			name:              "duration before triggered",
+			collection:        `{"evaluationStartMode": "duration", "evaluationDuration": "15m", "evaluationEndMode": "triggered"}`,
+			expectedStart:     "2022-04-07T10:46:00Z",
+			expectedEnd:       "2022-04-07T11:01:00Z",
+			expectedTimeframe: "15m",
+			expectedStartMode: "duration 15m0s",
+			expectedEndMode:   "triggered",
+		},
+		{
+			name:              "duration fallback",
+			collection:        `{"evaluationStartEventType": "sh.keptn.event.deployment.finished", "evaluationDuration": "1h30m", "evaluationEndEventType": "sh.keptn.event.test.finished"}`,
+			expectedStart:     "2022-04-07T09:00:00Z",
+			expectedEnd:       "2022-04-07T10:30:00Z",
+			expectedTimeframe: "1h30m",
+			expectedStartMode: "duration 1h30m0s",
+			expectedEndMode:   "last",
+			expectedWarnings:  []string{"no start event found for context 0dc1538a-2550-49b5-8319-30d57a83519f, stage \"staging\", type \"sh.keptn.event.deployment.finished\", starting evaluation 1h30m0s before its end"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollectionAt(t, triggeredTime, `{"stage": "staging", "collection": `+test.collection+`}`, events)
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+			assert.Equal(t, finishedEventData.Evaluation.Timeframe, test.expectedTimeframe)
+			assert.Equal(t, finishedEventData.Selection.Start.Strategy, test.expectedStartMode)
+			assert.Equal(t, finishedEventData.Selection.End.Strategy, test.expectedEndMode)
+			assert.DeepEqual(t, finishedEventData.Warnings, test.expectedWarnings)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerEndNow(t *testing.T) {
+	before := time.Now()
+
+	finishedEventData := runCollection(t, `{
+		"stage": "staging",
+		"collection": {"evaluationStartMode": "duration", "evaluationDuration": "1h", "evaluationEndMode": "now", "evaluationEndRounding": "none"}
+	}`, nil)
+
+	assertCollectionStatus(t, finishedEventData, "")
+	assert.Equal(t, finishedEventData.Evaluation.Timeframe, "1h")
+
+	end, err := time.Parse(time.RFC3339Nano, finishedEventData.UnroundedEvaluation.End)
+	assert.NilError(t, err)
+	assert.Assert(t, !end.Before(before.Truncate(time.Second)), "end %s is before collection", end)
+}
+
+func TestCollectionCloudEventHandlerTimestampSources(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("finished", testKeptnContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 7, 11, 10, 0, 0, time.UTC), `{"stage": "staging", "test": {"start": "2022-04-07T10:00:30Z", "end": "2022-04-07T10:45:10Z"}}`),
+	}
+
+	tests := []struct {
+		name                string
+		startTimestamp      string
+		endTimestamp        string
+		expectedStart       string
+		expectedEnd         string
+		expectedStartSource string
+		expectedEndSource   string
+		expectedWarnings    []string
+	}{
+		{
+			name:                "cloudevent",
+			startTimestamp:      `null`,
+			endTimestamp:        `"cloudevent"`,
+			expectedStart:       "2022-04-07T11:10:00Z",
+			expectedEnd:         "2022-04-07T11:10:00Z",
+			expectedStartSource: "cloudevent",
+			expectedEndSource:   "cloudevent",
+			expectedWarnings:    []string{"window is empty, start and end are 2022-04-07T11:10:00Z"},
+		},
+		{
+			name:                "field",
+			startTimestamp:      `{"source": "field", "path": "test.start"}`,
+			endTimestamp:        `{"source": "field", "path": "test.end"}`,
+			expectedStart:       "2022-04-07T10:00:00Z",
+			expectedEnd:         "2022-04-07T10:46:00Z",
+			expectedStartSource: "field test.start",
+			expectedEndSource:   "field test.end",
+		},
+		{
+			name:                "missing field",
+			startTimestamp:      `{"source": "field", "path": "test.begin"}`,
+			endTimestamp:        `{"source": "field", "path": "test.stop"}`,
+			expectedStart:       "2022-04-07T11:10:00Z",
+			expectedEnd:         "2022-04-07T11:10:00Z",
+			expectedStartSource: "cloudevent",
+			expectedEndSource:   "cloudevent",
+			expectedWarnings: []string{
+				"failed to read start timestamp from field test.begin, using CloudEvent time: field test.begin not found in event finished",
+				"failed to read end timestamp from field test.stop, using CloudEvent time: field test.stop not found in event finished",
+				"window is empty, start and end are 2022-04-07T11:10:00Z",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{
+				"stage": "staging",
+				"collection": {
+					"evaluationStartEventType": "sh.keptn.event.test.finished",
+					"evaluationStartTimestamp": `+test.startTimestamp+`,
+					"evaluationEndEventType": "sh.keptn.event.test.finished",
+					"evaluationEndTimestamp": `+test.endTimestamp+`,
+					"validation": {"policy": "warn"}
+				}
+			}`, events)
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+			assert.Equal(t, finishedEventData.Selection.Start.TimestampSource, test.expectedStartSource)
+			assert.Equal(t, finishedEventData.Selection.End.TimestampSource, test.expectedEndSource)
+			assert.DeepEqual(t, finishedEventData.Warnings, test.expectedWarnings)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerTrims(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("started", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("ramp-up", testKeptnContext, "sh.keptn.event.test.status.changed", time.Date(2022, 4, 7, 10, 0, 5, 0, time.UTC), `{"stage": "staging", "labels": {"phase": "rampUp"}}`),
+		newTestEvent("steady", testKeptnContext, "sh.keptn.event.test.status.changed", time.Date(2022, 4, 7, 10, 5, 0, 0, time.UTC), `{"stage": "staging", "labels": {"phase": "steady"}}`),
+		newTestEvent("ramp-down", testKeptnContext, "sh.keptn.event.test.status.changed", time.Date(2022, 4, 7, 10, 55, 0, 0, time.UTC), `{"stage": "staging", "labels": {"phase": "rampDown"}}`),
+		newTestEvent("finished", testKeptnContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name                string
+		trim                string
+		expectedStart       string
+		expectedEnd         string
+		expectedRaw         *WindowData
+		expectedAdjustments []string
+		expectedError       string
+	}{
+		{
+			name:          "none",
+			trim:          `null`,
+			expectedStart: "2022-04-07T10:00:00Z",
+			expectedEnd:   "2022-04-07T11:00:00Z",
+		},
+		{
+			name:                "durations",
+			trim:                `{"warmUp": "2m", "coolDown": "3m"}`,
+			expectedStart:       "2022-04-07T10:02:00Z",
+			expectedEnd:         "2022-04-07T10:57:00Z",
+			expectedRaw:         &WindowData{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T11:00:00Z"},
+			expectedAdjustments: []string{"trimmed warm-up of 2m0s", "trimmed cool-down of 3m0s"},
+		},
+		{
+			name: "markers",
+			trim: `{
+				"warmUp": {"eventType": "sh.keptn.event.test.status.changed", "label": "phase", "value": "steady"},
+				"coolDown": {"eventType": "sh.keptn.event.test.status.changed", "label": "phase", "value": "rampDown"}
+			}`,
+			expectedStart: "2022-04-07T10:05:00Z",
+			expectedEnd:   "2022-04-07T10:55:00Z",
+			expectedRaw:   &WindowData{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T11:00:00Z"},
+			expectedAdjustments: []string{
+				"trimmed warm-up of 5m0s up to marker event steady",
+				"trimmed cool-down of 5m0s from marker event ramp-down",
+			},
+		},
+		{
+			name:          "missing marker",
+			trim:          `{"coolDown": {"eventType": "sh.keptn.event.test.status.changed", "label": "phase", "value": "coolDown"}}`,
+			expectedError: "ABORTING. Failed to trim evaluation window: no cool-down marker event found within window",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{
+				"stage": "staging",
+				"collection": {
+					"evaluationStartEventType": "sh.keptn.event.test.started",
+					"evaluationEndEventType": "sh.keptn.event.test.finished",
+					"trim": `+test.trim+`
+				}
+			}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Evaluation.End, test.expectedEnd)
+			assert.DeepEqual(t, finishedEventData.RawEvaluation, test.expectedRaw)
+			assert.DeepEqual(t, finishedEventData.Adjustments, test.expectedAdjustments)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerNamedWindows(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("smoke-started", testKeptnContext, "sh.keptn.event.smoke-test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("smoke-finished", testKeptnContext, "sh.keptn.event.smoke-test.finished", time.Date(2022, 4, 7, 10, 5, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("load-started", testKeptnContext, "sh.keptn.event.load-test.started", time.Date(2022, 4, 7, 10, 10, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("load-finished", testKeptnContext, "sh.keptn.event.load-test.finished", time.Date(2022, 4, 7, 11, 10, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name            string
+		windows         string
+		expectedWindows []NamedWindowData
+		expectedLabels  map[string]string
+		expectedError   string
+	}{
+		{
+			name: "collected",
+			windows: `[
+				{"name": "smoke", "evaluationStartEventType": "sh.keptn.event.smoke-test.started", "evaluationEndEventType": "sh.keptn.event.smoke-test.finished"},
+				{"name": "load-test", "evaluationStartEventType": "sh.keptn.event.load-test.*", "evaluationEndEventType": "sh.keptn.event.load-test.*", "trim": {"warmUp": "5m"}}
+			]`,
+			expectedWindows: []NamedWindowData{
+				{
+					Name: "smoke",
+					CollectedWindowData: CollectedWindowData{
+						Evaluation:          EvaluationData{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T10:05:00Z", Timeframe: "5m"},
+						UnroundedEvaluation: WindowData{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T10:05:00Z"},
+						Selection: SelectionData{
+							Start: BoundarySelection{Strategy: "first", EventId: "smoke-started", EventType: "sh.keptn.event.smoke-test.started", Time: "2022-04-07T10:00:00Z", TimestampSource: "cloudevent", Rounding: "floor 1m0s"},
+							End:   BoundarySelection{Strategy: "last", EventId: "smoke-finished", EventType: "sh.keptn.event.smoke-test.finished", Time: "2022-04-07T10:05:00Z", TimestampSource: "cloudevent", Rounding: "ceil 1m0s"},
+						},
+					},
+				},
+				{
+					Name: "load-test",
+					CollectedWindowData: CollectedWindowData{
+						Evaluation:          EvaluationData{Start: "2022-04-07T10:15:00Z", End: "2022-04-07T11:10:00Z", Timeframe: "55m"},
+						RawEvaluation:       &WindowData{Start: "2022-04-07T10:10:00Z", End: "2022-04-07T11:10:00Z"},
+						UnroundedEvaluation: WindowData{Start: "2022-04-07T10:10:00Z", End: "2022-04-07T11:10:00Z"},
+						Selection: SelectionData{
+							Start: BoundarySelection{Strategy: "first", EventId: "load-started", EventType: "sh.keptn.event.load-test.started", Time: "2022-04-07T10:10:00Z", TimestampSource: "cloudevent", Rounding: "floor 1m0s"},
+							End:   BoundarySelection{Strategy: "last", EventId: "load-finished", EventType: "sh.keptn.event.load-test.finished", Time: "2022-04-07T11:10:00Z", TimestampSource: "cloudevent", Rounding: "ceil 1m0s"},
+						},
+						Adjustments: []string{"trimmed warm-up of 5m0s"},
+					},
+				},
+			},
+			expectedLabels: map[string]string{
+				"buildId":                    "shall-not-be-overwritten",
+				"WINDOW_SMOKE_START":         "2022-04-07T10:00:00Z",
+				"WINDOW_SMOKE_END":           "2022-04-07T10:05:00Z",
+				"WINDOW_SMOKE_TIMEFRAME":     "5m",
+				"WINDOW_LOAD_TEST_START":     "2022-04-07T10:15:00Z",
+				"WINDOW_LOAD_TEST_END":       "2022-04-07T11:10:00Z",
+				"WINDOW_LOAD_TEST_TIMEFRAME": "55m",
+			},
+		},
+		{
+			name:          "no events",
+			windows:       `[{"name": "soak", "evaluationStartEventType": "sh.keptn.event.soak-test.started"}]`,
+			expectedError: "ABORTING. Failed to collect window \"soak\": Failed to collect start timestamps for context 0dc1538a-2550-49b5-8319-30d57a83519f, stage \"staging\", type \"sh.keptn.event.soak-test.started\": no timestamps found",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{"stage": "staging", "labels": {"buildId": "shall-not-be-overwritten"}, "collection": {"windows": `+test.windows+`}}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, "2022-04-07T10:00:00Z")
+			assert.Equal(t, finishedEventData.Evaluation.End, "2022-04-07T11:10:00Z")
+			assert.DeepEqual(t, finishedEventData.Windows, test.expectedWindows)
+			assert.DeepEqual(t, finishedEventData.Labels, test.expectedLabels)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerSplit(t *testing.T) {
+	events := []cloudevents.Event{
+		newTestEvent("soak-started", testKeptnContext, "sh.keptn.event.soak-test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{"stage": "staging"}`),
+		newTestEvent("soak-finished", testKeptnContext, "sh.keptn.event.soak-test.finished", time.Date(2022, 4, 7, 11, 10, 0, 0, time.UTC), `{"stage": "staging"}`),
+	}
+
+	tests := []struct {
+		name               string
+		split              string
+		expectedSubWindows []EvaluationData
+		expectedLabels     map[string]string
+		expectedError      string
+	}{
+		{
+			name:  "count",
+			split: `{"count": 2}`,
+			expectedSubWindows: []EvaluationData{
+				{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T10:35:00Z", Timeframe: "35m"},
+				{Start: "2022-04-07T10:35:00Z", End: "2022-04-07T11:10:00Z", Timeframe: "35m"},
+			},
+			expectedLabels: map[string]string{
+				"buildId":               "shall-not-be-overwritten",
+				"SUBWINDOW_COUNT":       "2",
+				"SUBWINDOW_1_START":     "2022-04-07T10:00:00Z",
+				"SUBWINDOW_1_END":       "2022-04-07T10:35:00Z",
+				"SUBWINDOW_1_TIMEFRAME": "35m",
+				"SUBWINDOW_2_START":     "2022-04-07T10:35:00Z",
+				"SUBWINDOW_2_END":       "2022-04-07T11:10:00Z",
+				"SUBWINDOW_2_TIMEFRAME": "35m",
+			},
+		},
+		{
+			name:  "duration",
+			split: `{"duration": "30m"}`,
+			expectedSubWindows: []EvaluationData{
+				{Start: "2022-04-07T10:00:00Z", End: "2022-04-07T10:30:00Z", Timeframe: "30m"},
+				{Start: "2022-04-07T10:30:00Z", End: "2022-04-07T11:00:00Z", Timeframe: "30m"},
+				{Start: "2022-04-07T11:00:00Z", End: "2022-04-07T11:10:00Z", Timeframe: "10m"},
+			},
+			expectedLabels: map[string]string{
+				"buildId":               "shall-not-be-overwritten",
+				"SUBWINDOW_COUNT":       "3",
+				"SUBWINDOW_1_START":     "2022-04-07T10:00:00Z",
+				"SUBWINDOW_1_END":       "2022-04-07T10:30:00Z",
+				"SUBWINDOW_1_TIMEFRAME": "30m",
+				"SUBWINDOW_2_START":     "2022-04-07T10:30:00Z",
+				"SUBWINDOW_2_END":       "2022-04-07T11:00:00Z",
+				"SUBWINDOW_2_TIMEFRAME": "30m",
+				"SUBWINDOW_3_START":     "2022-04-07T11:00:00Z",
+				"SUBWINDOW_3_END":       "2022-04-07T11:10:00Z",
+				"SUBWINDOW_3_TIMEFRAME": "10m",
+			},
+		},
+		{
+			name:          "too many windows",
+			split:         `{"duration": "1s"}`,
+			expectedError: "ABORTING. Failed to split evaluation window: split of window of 1h10m0s into windows of 1s exceeds maximum of 100 windows",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{"stage": "staging", "labels": {"buildId": "shall-not-be-overwritten"}, "collection": {
+				"evaluationStartEventType": "sh.keptn.event.soak-test.*",
+				"evaluationEndEventType": "sh.keptn.event.soak-test.*",
+				"split": `+test.split+`
+			}}`, events)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Timeframe, "1h10m")
+			assert.DeepEqual(t, finishedEventData.SubWindows, test.expectedSubWindows)
+			assert.DeepEqual(t, finishedEventData.Labels, test.expectedLabels)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerBaseline(t *testing.T) {
+	scope := `"project": "simplenode-gitlab", "stage": "staging", "service": "simplenodeservice"`
+
+	testEvents := []cloudevents.Event{
+		newTestEvent("test-started", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 12, 0, 0, 0, time.UTC), `{`+scope+`}`),
+		newTestEvent("test-finished", testKeptnContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 7, 12, 30, 0, 0, time.UTC), `{`+scope+`}`),
+	}
+
+	previousPassed := newTestEvent("previous-passed", "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01", "sh.keptn.event.evaluation.finished", time.Date(2022, 4, 6, 11, 0, 0, 0, time.UTC),
+		`{`+scope+`, "status": "succeeded", "result": "pass", "evaluation": {"timeStart": "2022-04-06T10:00:00Z", "timeEnd": "2022-04-06T10:30:00Z"}}`)
+	previousFailed := newTestEvent("previous-failed", "c7d9e0f1-5a2b-4c3d-8e4f-6a7b8c9d0e12", "sh.keptn.event.collection.finished", time.Date(2022, 4, 6, 15, 0, 0, 0, time.UTC),
+		`{`+scope+`, "status": "succeeded", "result": "fail", "evaluation": {"start": "2022-04-06T14:00:00Z", "end": "2022-04-06T14:30:00Z"}}`)
+	otherService := newTestEvent("other-service", "d2e3f4a5-6b7c-4d8e-9f0a-1b2c3d4e5f60", "sh.keptn.event.collection.finished", time.Date(2022, 4, 6, 16, 0, 0, 0, time.UTC),
+		`{"project": "simplenode-gitlab", "stage": "staging", "service": "other", "status": "succeeded", "result": "pass", "evaluation": {"start": "2022-04-06T15:00:00Z", "end": "2022-04-06T15:30:00Z"}}`)
+
+	expiredPassed := newTestEvent("expired-passed", "e5f6a7b8-9c0d-4e1f-8a2b-3c4d5e6f7a80", "sh.keptn.event.evaluation.finished", time.Date(2022, 3, 1, 11, 0, 0, 0, time.UTC),
+		`{`+scope+`, "status": "succeeded", "result": "pass", "evaluation": {"timeStart": "2022-03-01T10:00:00Z", "timeEnd": "2022-03-01T10:30:00Z"}}`)
+
+	tests := []struct {
+		name             string
+		baseline         string
+		previousEvents   []cloudevents.Event
+		expectedBaseline *BaselineData
+		expectedLabels   map[string]string
+		expectedWarnings []string
+	}{
+		{
+			name:           "found",
+			baseline:       `{}`,
+			previousEvents: []cloudevents.Event{previousPassed, previousFailed, otherService},
+			expectedBaseline: &BaselineData{
+				Context:    "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01",
+				EventId:    "previous-passed",
+				EventType:  "sh.keptn.event.evaluation.finished",
+				Evaluation: EvaluationData{Start: "2022-04-06T10:00:00Z", End: "2022-04-06T10:30:00Z", Timeframe: "30m"},
+			},
+			expectedLabels: map[string]string{
+				"buildId":                 "shall-not-be-overwritten",
+				"BASELINE_CONTEXT":        "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01",
+				"BASELINE_START":          "2022-04-06T10:00:00Z",
+				"BASELINE_END":            "2022-04-06T10:30:00Z",
+				"BASELINE_TIMEFRAME":      "30m",
+				"SYNTHETIC_BATCH_IDS":     "",
+				"SYNTHETIC_EXECUTION_IDS": "",
+			},
+		},
+		{
+			name:           "not found",
+			baseline:       `{"eventType": "sh.keptn.event.collection.finished"}`,
+			previousEvents: []cloudevents.Event{previousPassed, previousFailed, otherService},
+			expectedLabels: map[string]string{
+				"buildId":                 "shall-not-be-overwritten",
+				"SYNTHETIC_BATCH_IDS":     "",
+				"SYNTHETIC_EXECUTION_IDS": "",
+			},
+			expectedWarnings: []string{"no baseline found for project \"simplenode-gitlab\", stage \"staging\", service \"simplenodeservice\", type \"sh.keptn.event.collection.finished\", from 2022-03-08T12:05:28Z"},
+		},
+		{
+			// Runs are looked up 30 days back from the collection by default
+			name:           "beyond default maximum age",
+			baseline:       `{"eventType": "sh.keptn.event.evaluation.finished"}`,
+			previousEvents: []cloudevents.Event{expiredPassed},
+			expectedLabels: map[string]string{
+				"buildId":                 "shall-not-be-overwritten",
+				"SYNTHETIC_BATCH_IDS":     "",
+				"SYNTHETIC_EXECUTION_IDS": "",
+			},
+			expectedWarnings: []string{"no baseline found for project \"simplenode-gitlab\", stage \"staging\", service \"simplenodeservice\", type \"sh.keptn.event.evaluation.finished\", from 2022-03-08T12:05:28Z"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			finishedEventData := runCollection(t, `{`+scope+`, "labels": {"buildId": "shall-not-be-overwritten"}, "collection": {
+				"evaluationStartEventType": "sh.keptn.event.test.started",
+				"evaluationEndEventType": "sh.keptn.event.test.finished",
+				"baseline": `+test.baseline+`
+			}}`, append(append([]cloudevents.Event{}, testEvents...), test.previousEvents...))
+
+			assertCollectionStatus(t, finishedEventData, "")
+			assert.Equal(t, finishedEventData.Evaluation.Start, "2022-04-07T12:00:00Z")
+			assert.DeepEqual(t, finishedEventData.Baseline, test.expectedBaseline)
+			assert.DeepEqual(t, finishedEventData.Labels, test.expectedLabels)
+			assert.DeepEqual(t, finishedEventData.Warnings, test.expectedWarnings)
+		})
+	}
+}
+
+func TestCollectionCloudEventHandlerContextReferences(t *testing.T) {
+	const (
+		outdatedContext = "9e2f6c1d-7b3a-4f8e-a5d2-1c4b6e8f0a23"
+		olderContext    = "b1a4b2e6-0c41-4d5e-9d1c-3f3f8f9a6a01"
+		latestContext   = "c7d9e0f1-5a2b-4c3d-8e4f-6a7b8c9d0e12"
+	)
+
+	service := `"project": "simplenode-gitlab", "service": "simplenodeservice"`
+
+	events := []cloudevents.Event{
+		newTestEvent("outdated-finished", outdatedContext, "sh.keptn.event.test.finished", time.Date(2022, 3, 1, 10, 30, 0, 0, time.UTC), `{`+service+`, "stage": "hardening", "result": "pass", "labels": {"buildId": "0.9.0"}}`),
+		newTestEvent("older-started", olderContext, "sh.keptn.event.test.started", time.Date(2022, 4, 6, 10, 0, 0, 0, time.UTC), `{`+service+`, "stage": "hardening", "labels": {"buildId": "1.0.0"}}`),
+		newTestEvent("older-finished", olderContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 6, 10, 30, 0, 0, time.UTC), `{`+service+`, "stage": "hardening", "result": "pass", "labels": {"buildId": "1.0.0"}}`),
+		newTestEvent("latest-started", latestContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 10, 0, 0, 0, time.UTC), `{`+service+`, "stage": "hardening", "labels": {"buildId": "1.0.1"}}`),
+		newTestEvent("latest-finished", latestContext, "sh.keptn.event.test.finished", time.Date(2022, 4, 7, 10, 45, 0, 0, time.UTC), `{`+service+`, "stage": "hardening", "result": "fail", "labels": {"buildId": "1.0.1"}}`),
+		newTestEvent("latest-delivery-finished", latestContext, "sh.keptn.event.hardening.delivery.finished", time.Date(2022, 4, 7, 10, 50, 0, 0, time.UTC), `{`+service+`, "stage": "hardening"}`),
+		newTestEvent("current-evaluation-triggered", testKeptnContext, "sh.keptn.event.staging.evaluation.triggered", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC), `{`+service+`, "stage": "staging"}`),
+		newTestEvent("current-started", testKeptnContext, "sh.keptn.event.test.started", time.Date(2022, 4, 7, 11, 0, 0, 0, time.UTC), `{`+service+`, "stage": "staging", "labels": {"buildId": "1.0.1"}}`),
+	}
+
+	tests := []struct {
+		name                 string
+		startContext         string
+		triggeredId          string
+		expectedStart        string
+		expectedStartEventId string
+		expectedError        string
+	}{
+		{
+			name:                 "latest",
+			startContext:         `{"ref": "latest", "stage": "hardening", "eventType": "sh.keptn.event.test.finished"}`,
+			expectedStart:        "2022-04-07T10:00:00Z",
+			expectedStartEventId: "latest-started",
+		},
+		{
+			name:                 "latest passed",
+			startContext:         `{"ref": "latest", "stage": "hardening", "eventType": "sh.keptn.event.test.finished", "result": "pass"}`,
+			expectedStart:        "2022-04-06T10:00:00Z",
+			expectedStartEventId: "older-started",
+		},
+		{
+			name:                 "label",
+			startContext:         `{"ref": "label", "label": "buildId", "value": "$LABEL.buildId"}`,
+			expectedStart:        "2022-04-06T10:00:00Z",
+			expectedStartEventId: "older-started",
+		},
+		{
+			name:                 "triggering",
+			startContext:         `{"ref": "triggering"}`,
+			triggeredId:          "latest-delivery-finished",
+			expectedStart:        "2022-04-07T10:00:00Z",
+			expectedStartEventId: "latest-started",
+		},
+		{
+			name:          "triggering beyond maximum age",
+			startContext:  `{"ref": "triggering", "maxAge": "1m"}`,
+			triggeredId:   "latest-delivery-finished",
+			expectedError: "failed to resolve evaluation start context: event latest-delivery-finished triggering context 0dc1538a-2550-49b5-8319-30d57a83519f not found in another context of project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-04-07T10:54:00Z, to 2022-04-07T10:55:00Z",
+		},
+		{
+			name:          "no match",
+			startContext:  `{"ref": "label", "label": "buildId", "value": "2.0.0"}`,
+			expectedError: "failed to resolve evaluation start context: no context labeled buildId=\"2.0.0\" found for project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-03-08T12:05:28Z",
+		},
+		{
+			name:          "label beyond default maximum age",
+			startContext:  `{"ref": "label", "label": "buildId", "value": "0.9.0"}`,
+			expectedError: "failed to resolve evaluation start context: no context labeled buildId=\"0.9.0\" found for project \"simplenode-gitlab\", service \"simplenodeservice\", from 2022-03-08T12:05:28Z",
+		},
+		{
+			name:          "latest beyond maximum age",
+			startContext:  `{"ref": "latest", "stage": "hardening", "eventType": "sh.keptn.event.test.finished", "maxAge": "1h"}`,
+			expectedError: "failed to resolve evaluation start context: no context found for project \"simplenode-gitlab\", stage \"hardening\", service \"simplenodeservice\", type \"sh.keptn.event.test.finished\", from 2022-04-07T11:05:28Z",
+		},
+		{
+			name:          "not triggered",
+			startContext:  `{"ref": "triggering"}`,
+			expectedError: "failed to resolve evaluation start context: sequence of context 0dc1538a-2550-49b5-8319-30d57a83519f was not triggered by another event",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testEvents := events
+			if test.triggeredId != "" {
+				sequenceTriggered := newTestEvent("current-delivery-triggered", testKeptnContext, "sh.keptn.event.staging.delivery.triggered", time.Date(2022, 4, 7, 10, 55, 0, 0, time.UTC), `{`+service+`, "stage": "staging"}`)
+				sequenceTriggered.SetExtension("triggeredid", test.triggeredId)
+
+				testEvents = append(append([]cloudevents.Event{}, events...), sequenceTriggered)
+			}
+
+			finishedEventData := runCollection(t, `{`+service+`, "stage": "staging", "labels": {"buildId": "1.0.0"}, "collection": {
+				"evaluationStartContext": `+test.startContext+`,
+				"evaluationStartEventType": "sh.keptn.event.test.started",
+				"evaluationEndEventType": "sh.keptn.event.test.started",
+				"evaluationEndStrategy": "first"
+			}}`, testEvents)
+
+			if !assertCollectionStatus(t, finishedEventData, test.expectedError) {
+				return
+			}
+
+			assert.Equal(t, finishedEventData.Evaluation.Start, test.expectedStart)
+			assert.Equal(t, finishedEventData.Selection.Start.EventId, test.expectedStartEventId)
+			assert.Equal(t, finishedEventData.Selection.End.EventId, "current-started")
+		})
+	}
 }
@@ -1,13 +1,15 @@
 package eventHandler
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2" // make sure to use v2 cloudevents here
-	"github.com/cloudevents/sdk-go/v2/event"
 	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
 	"github.com/keptn/go-utils/pkg/lib/keptn"
 	keptnv2 "github.com/keptn/go-utils/pkg/lib/v0_2_0"
@@ -31,9 +33,59 @@ type EvaluationData struct {
 	Timeframe string `json:"timeframe"`
 }
 
+// WindowData is a window with sub-second precision.
+type WindowData struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// BoundarySelection reports the strategy a boundary was selected by, the event it picked, where its
+// timestamp was read from and how it was rounded.
+type BoundarySelection struct {
+	Strategy        string `json:"strategy"`
+	EventId         string `json:"eventId,omitempty"`
+	EventType       string `json:"eventType,omitempty"`
+	Time            string `json:"time"`
+	TimestampSource string `json:"timestampSource,omitempty"`
+	Rounding        string `json:"rounding"`
+}
+
+type SelectionData struct {
+	Start BoundarySelection `json:"start"`
+	End   BoundarySelection `json:"end"`
+}
+
+// CollectedWindowData is an evaluation window along with how its boundaries were selected and adjusted.
+type CollectedWindowData struct {
+	Evaluation          EvaluationData   `json:"evaluation"`
+	SubWindows          []EvaluationData `json:"subWindows,omitempty"`
+	RawEvaluation       *WindowData      `json:"rawEvaluation,omitempty"`
+	UnroundedEvaluation WindowData       `json:"unroundedEvaluation"`
+	Selection           SelectionData    `json:"selection"`
+	Adjustments         []string         `json:"adjustments,omitempty"`
+}
+
+// NamedWindowData is a named evaluation window.
+type NamedWindowData struct {
+	Name string `json:"name"`
+	CollectedWindowData
+}
+
+// BaselineData is the evaluation window of the most recent successful run, along with the context
+// and the finished event it was read from.
+type BaselineData struct {
+	Context    string         `json:"context"`
+	EventId    string         `json:"eventId"`
+	EventType  string         `json:"eventType"`
+	Evaluation EvaluationData `json:"evaluation"`
+}
+
 type CollectionSuccessfulEventData struct {
 	keptnv2.EventData
-	Evaluation EvaluationData `json:"evaluation"`
+	CollectedWindowData
+	Windows  []NamedWindowData `json:"windows,omitempty"`
+	Baseline *BaselineData     `json:"baseline,omitempty"`
+	Warnings []string          `json:"warnings,omitempty"`
 }
 
 type CollectionUnsuccessfulEventData struct {
@@ -41,6 +93,7 @@ type CollectionUnsuccessfulEventData struct {
 }
 
 func CollectionCloudEventHandler(
+	ctx context.Context,
 	myKeptn *keptnv2.Keptn,
 	incomingEvent cloudevents.Event,
 	// data *CollectionEventData,
@@ -64,143 +117,265 @@ func CollectionCloudEventHandler(
 		return err
 	}
 
-	var collectionStartEventsInContext []event.Event
-	var collectionStartEventFilter string
-	var collectionStartStageFilter string
-	var collectionEndEventsInContext []event.Event
-	var collectionEndEventFilter string
-	var collectionEndStageFilter string
-	var syntheticTestFinishedEventsInContext []event.Event
-	var syntheticTestFinishedEventFilter string
-	var syntheticTestFinishedStageFilter string
+	if collectionEventDataIface.GetBypassCache() {
+		ctx = collector.WithCacheBypass(ctx)
+	}
+
+	now := time.Now()
 
-	collectionStartContext, err := collectionEventDataIface.GetEvaluationStartContext()
+	err = resolveContextReferences(ctx, collectorIface, incomingEvent, collectionEventDataIface, getLookupTime(incomingEvent, now))
 	if err != nil {
 		log.Println(err.Error())
 		return sendTaskFail(myKeptn, eventData, serviceName, err)
 	}
 
-	collectionStartEventFilter = collectionEventDataIface.GetEvaluationStartEventFilter()
-	collectionStartStageFilter = collectionEventDataIface.GetEvaluationStartStageFilter()
-
-	collectionStartEventsInContext, err = collectorIface.GetEvents(collectionStartContext)
+	syntheticTestFinishedFilter, err := collectionEventDataIface.GetSyntheticTestFinishedFilter(now)
 	if err != nil {
 		log.Println(err.Error())
 		return sendTaskFail(myKeptn, eventData, serviceName, err)
 	}
 
-	collectionEndContext, err := collectionEventDataIface.GetEvaluationEndContext()
+	syntheticTestFinishedExpression, err := compileExpression("synthetic test finished", collectionEventDataIface.GetSyntheticTestFinishedExpression())
 	if err != nil {
 		log.Println(err.Error())
 		return sendTaskFail(myKeptn, eventData, serviceName, err)
 	}
 
-	collectionEndEventFilter = collectionEventDataIface.GetEvaluationEndEventFilter()
-	collectionEndStageFilter = collectionEventDataIface.GetEvaluationEndStageFilter()
-
-	isSameContextAsStart := collectionEndContext == collectionStartContext
-
-	if isSameContextAsStart {
-		collectionEndEventsInContext = collectionStartEventsInContext
-	} else {
-		collectionEndEventsInContext, err = collectorIface.GetEvents(collectionEndContext)
-		if err != nil {
-			log.Println(err.Error())
-			return sendTaskFail(myKeptn, eventData, serviceName, err)
-		}
+	namedCollections, err := collectionEventDataIface.GetNamedWindows()
+	if err != nil {
+		log.Println(err.Error())
+		return sendTaskFail(myKeptn, eventData, serviceName, err)
 	}
 
-	syntheticTestFinishedContext, err := collectionEventDataIface.GetSyntheticTestFinishedContext()
+	baselineFilters, err := collectionEventDataIface.GetBaselineFilters(getLookupTime(incomingEvent, now))
 	if err != nil {
 		log.Println(err.Error())
 		return sendTaskFail(myKeptn, eventData, serviceName, err)
 	}
 
-	syntheticTestFinishedEventFilter = collectionEventDataIface.GetSyntheticTestFinishedEventFilter()
-	syntheticTestFinishedStageFilter = collectionEventDataIface.GetSyntheticTestFinishedStageFilter()
+	primaryWindow, err := collectWindow(ctx, collectorIface, incomingEvent, collectionEventDataIface, now, syntheticTestFinishedFilter)
+	if err != nil {
+		errMsg := fmt.Errorf("ABORTING. %s", err.Error())
+		log.Println(errMsg.Error())
+		return sendTaskFail(myKeptn, eventData, serviceName, errMsg)
+	}
 
-	isSameContextAsStart = syntheticTestFinishedContext == collectionStartContext
-	isSameContextAsEnd := syntheticTestFinishedContext == collectionEndContext
+	warnings := primaryWindow.warnings
+	eventsByFilter := primaryWindow.eventsByFilter
 
-	if isSameContextAsStart {
-		syntheticTestFinishedEventsInContext = collectionStartEventsInContext
-	} else if isSameContextAsEnd {
-		syntheticTestFinishedEventsInContext = collectionEndEventsInContext
-	} else {
-		syntheticTestFinishedEventsInContext, err = collectorIface.GetEvents(syntheticTestFinishedContext)
-		if err != nil {
-			log.Println(err.Error())
-			return sendTaskFail(myKeptn, eventData, serviceName, err)
-		}
-	}
+	// Filters as scoped while collecting the primary window
+	syntheticTestFinishedFilter = primaryWindow.additionalFilters[0]
 
-	// Evaluation start is earliest event timestamp
-	evaluationStartEvents := collectorIface.ParseEvents(collectionStartEventsInContext, collectionStartEventFilter, collectionStartStageFilter)
+	var baselineData *BaselineData
+	if len(baselineFilters) > 0 {
+		currentContext, _ := incomingEvent.Extensions()["shkeptncontext"].(string)
 
-	evaluationStart, err := collectorIface.CollectEarliestTime(evaluationStartEvents, true)
-	if err != nil {
-		errMsg := fmt.Errorf("ABORTING. Failed to collect start timestamps for context %s, filtered by \"%s\": %s", collectionStartContext, collectionStartEventFilter, err.Error())
-		log.Println(errMsg.Error())
-		return sendTaskFail(myKeptn, eventData, serviceName, errMsg)
+		var baselineWarnings []string
+		baselineData, baselineWarnings = lookUpBaseline(ctx, collectorIface, baselineFilters, currentContext)
+		warnings = append(warnings, baselineWarnings...)
 	}
 
-	// Evaluation end is latest event timestamp
-	evaluationEndEvents := collectorIface.ParseEvents(collectionEndEventsInContext, collectionEndEventFilter, collectionEndStageFilter)
-	evaluationEnd, err := collectorIface.CollectLatestTime(evaluationEndEvents, true)
+	syntheticTestFinishedEvents, expressionWarnings := selectByExpression(syntheticTestFinishedExpression, eventsByFilter[syntheticTestFinishedFilter])
+	warnings = append(warnings, expressionWarnings...)
+
+	syntheticTestFinishedEvents, err = selectExecution(collectorIface, syntheticTestFinishedEvents, collectionEventDataIface.GetSyntheticTestFinishedExecution())
 	if err != nil {
-		errMsg := fmt.Errorf("ABORTING. Failed to collect end timestamps for context %s, filtered by \"%s\": %s", collectionEndContext, collectionEndEventFilter, err.Error())
+		errMsg := fmt.Errorf("ABORTING. Failed to select synthetic test execution for %s: %s", syntheticTestFinishedFilter.String(), err.Error())
 		log.Println(errMsg.Error())
 		return sendTaskFail(myKeptn, eventData, serviceName, errMsg)
 	}
 
-	syntheticTestFinishedEvents := collectorIface.ParseEvents(syntheticTestFinishedEventsInContext, syntheticTestFinishedEventFilter, syntheticTestFinishedStageFilter)
-
 	isSyntheticTestFinishedEventFound := len(syntheticTestFinishedEvents) > 0
 
 	if isSyntheticTestFinishedEventFound {
-		executionIds, err := collectorIface.CollectExecutionIds(syntheticTestFinishedEvents)
+		executionIds := collectorIface.CollectExecutionIds(syntheticTestFinishedEvents)
+		batchIds := collectorIface.CollectBatchIds(syntheticTestFinishedEvents)
+
+		labels := eventData.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+
+		labels["SYNTHETIC_EXECUTION_IDS"] = ""
+		if len(executionIds) > 0 {
+			labels["SYNTHETIC_EXECUTION_IDS"] = strings.Join(executionIds, ",")
+		}
+
+		labels["SYNTHETIC_BATCH_IDS"] = ""
+		if len(batchIds) > 0 {
+			labels["SYNTHETIC_BATCH_IDS"] = strings.Join(batchIds, ",")
+		}
+
+		eventData.SetLabels(labels)
+	}
+
+	namedWindows := []NamedWindowData{}
+	for _, namedCollection := range namedCollections {
+		err := resolveContextReferences(ctx, collectorIface, incomingEvent, namedCollection.Data, getLookupTime(incomingEvent, now))
 		if err != nil {
-			errMsg := fmt.Errorf("ABORTING. Failed to collect execution ids for context %s, filtered by \"%s\": %s", syntheticTestFinishedContext, syntheticTestFinishedEventFilter, err.Error())
+			errMsg := fmt.Errorf("ABORTING. Failed to collect window \"%s\": %s", namedCollection.Name, err.Error())
 			log.Println(errMsg.Error())
 			return sendTaskFail(myKeptn, eventData, serviceName, errMsg)
 		}
 
-		batchIds, err := collectorIface.CollectBatchIds(syntheticTestFinishedEvents)
+		namedWindow, err := collectWindow(ctx, collectorIface, incomingEvent, namedCollection.Data, now)
 		if err != nil {
-			errMsg := fmt.Errorf("ABORTING. Failed to collect batch ids for context %s, filtered by \"%s\": %s", syntheticTestFinishedContext, syntheticTestFinishedEventFilter, err.Error())
+			errMsg := fmt.Errorf("ABORTING. Failed to collect window \"%s\": %s", namedCollection.Name, err.Error())
 			log.Println(errMsg.Error())
 			return sendTaskFail(myKeptn, eventData, serviceName, errMsg)
 		}
 
+		for _, warning := range namedWindow.warnings {
+			warnings = append(warnings, fmt.Sprintf("window %s: %s", namedCollection.Name, warning))
+		}
+
+		namedWindows = append(namedWindows, NamedWindowData{
+			Name:                namedCollection.Name,
+			CollectedWindowData: namedWindow.window,
+		})
+	}
+
+	if len(namedWindows) > 0 || len(primaryWindow.window.SubWindows) > 0 || baselineData != nil {
 		labels := eventData.GetLabels()
 		if labels == nil {
 			labels = map[string]string{}
 		}
 
-		labels["SYNTHETIC_EXECUTION_IDS"] = ""
-		if len(executionIds) > 0 {
-			labels["SYNTHETIC_EXECUTION_IDS"] = strings.Join(executionIds, ",")
+		setSubWindowLabels(labels, "SUBWINDOW", primaryWindow.window.SubWindows)
+
+		if baselineData != nil {
+			setEvaluationLabels(labels, "BASELINE", baselineData.Evaluation)
+			labels["BASELINE_CONTEXT"] = baselineData.Context
 		}
 
-		labels["SYNTHETIC_BATCH_IDS"] = ""
-		if len(batchIds) > 0 {
-			labels["SYNTHETIC_BATCH_IDS"] = strings.Join(batchIds, ",")
+		for _, namedWindow := range namedWindows {
+			labelPrefix := windowLabelPrefix(namedWindow.Name)
+			setEvaluationLabels(labels, labelPrefix, namedWindow.Evaluation)
+			setSubWindowLabels(labels, labelPrefix+"_SUBWINDOW", namedWindow.SubWindows)
 		}
 
 		eventData.SetLabels(labels)
 	}
 
+	for _, warning := range warnings {
+		log.Println(warning)
+	}
+
 	successfulEventData := &CollectionSuccessfulEventData{
-		EventData: eventData,
-		Evaluation: EvaluationData{
-			Start: evaluationStart.Format(time.RFC3339),
-			End:   evaluationEnd.Format(time.RFC3339),
-		},
+		EventData:           eventData,
+		CollectedWindowData: primaryWindow.window,
+		Windows:             namedWindows,
+		Baseline:            baselineData,
+		Warnings:            warnings,
 	}
 
 	return sendTaskSuccess(myKeptn, successfulEventData, serviceName)
 }
 
+/**
+ * Reports a collection whose data can't be decoded, e.g. because of an invalid strategy, through an
+ * errored finished event instead of dropping the triggered event.
+ */
+func InvalidCollectionCloudEventHandler(
+	myKeptn *keptnv2.Keptn,
+	incomingEvent cloudevents.Event,
+	serviceName string,
+	decodeErr error,
+) error {
+	log.Printf("Handling invalid %s Event: %s", incomingEvent.Type(), incomingEvent.Context.GetID())
+
+	// Project, stage and service are decoded on their own, so the error is reported where it occurred
+	eventData := keptnv2.EventData{}
+	_ = json.Unmarshal(incomingEvent.Data(), &eventData)
+
+	_, err := myKeptn.SendTaskStartedEvent(&eventData, serviceName)
+	if err != nil {
+		errMsg := fmt.Sprintf("Failed to send task started CloudEvent (%s), aborting...", err.Error())
+		log.Println(errMsg)
+		return err
+	}
+
+	log.Println(decodeErr.Error())
+	return sendTaskFail(myKeptn, eventData, serviceName, decodeErr)
+}
+
+/**
+ * Returns the time the age of baselines and referenced contexts is measured from: the time the
+ * collection was triggered, or now if the triggering event has no time.
+ */
+func getLookupTime(incomingEvent cloudevents.Event, now time.Time) time.Time {
+	if incomingEvent.Time().IsZero() {
+		return now
+	}
+
+	return incomingEvent.Time()
+}
+
+/**
+ * Sets start, end and timeframe of the evaluation as labels, e.g. WINDOW_LOAD_START.
+ */
+func setEvaluationLabels(labels map[string]string, prefix string, evaluation EvaluationData) {
+	labels[prefix+"_START"] = evaluation.Start
+	labels[prefix+"_END"] = evaluation.End
+	labels[prefix+"_TIMEFRAME"] = evaluation.Timeframe
+}
+
+/**
+ * Sets the sub-windows as labels indexed starting at 1, e.g. SUBWINDOW_1_START, along with their
+ * number, e.g. SUBWINDOW_COUNT.
+ */
+func setSubWindowLabels(labels map[string]string, prefix string, subWindows []EvaluationData) {
+	if len(subWindows) == 0 {
+		return
+	}
+
+	labels[prefix+"_COUNT"] = strconv.Itoa(len(subWindows))
+
+	for i, subWindow := range subWindows {
+		setEvaluationLabels(labels, fmt.Sprintf("%s_%d", prefix, i+1), subWindow)
+	}
+}
+
+/**
+ * Compiles the expression events of a boundary are selected by. If none was provided, nil is returned.
+ */
+func compileExpression(boundary string, source string) (*collector.EventExpression, error) {
+	if source == "" {
+		return nil, nil
+	}
+
+	expression, err := collector.CompileEventExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s expression: %w", boundary, err)
+	}
+
+	return expression, nil
+}
+
+/**
+ * Restricts events to the ones the expression evaluates to true for. If no expression was
+ * provided, events are returned as is.
+ */
+func selectByExpression(expression *collector.EventExpression, events []*collector.EventEnvelope) ([]*collector.EventEnvelope, []string) {
+	if expression == nil {
+		return events, []string{}
+	}
+
+	return expression.Filter(events)
+}
+
+/**
+ * Restricts events to a single execution. If no selector was provided or no events were found,
+ * events are returned as is.
+ */
+func selectExecution(collectorIface collector.CollectorIface, events []*collector.EventEnvelope, selector string) ([]*collector.EventEnvelope, error) {
+	if selector == "" || len(events) == 0 {
+		return events, nil
+	}
+
+	return collectorIface.SelectExecution(events, selector)
+}
+
 func sendTaskSuccess(myKeptn *keptnv2.Keptn, data keptn.EventProperties, serviceName string) error {
 	_, err := myKeptn.SendTaskFinishedEvent(data, serviceName)
 	return err
@@ -0,0 +1,86 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+/**
+ * Restricts filters on the stage of the current context to the sequence the incoming event belongs to.
+ * The sequence starts with the latest sh.keptn.event.<stage>.<sequence>.triggered event of the stage
+ * that was sent before the incoming event. The scoped filters are returned in the order of the given ones.
+ */
+func scopeToSequence(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	incomingEvent cloudevents.Event,
+	stage string,
+	filters ...collector.EventFilter,
+) ([]collector.EventFilter, error) {
+	keptnContext, ok := incomingEvent.Extensions()["shkeptncontext"].(string)
+	if !ok {
+		return nil, fmt.Errorf("error parsing Keptn context")
+	}
+
+	stageEvents, _, err := collectorIface.GetFilteredEvents(ctx, collector.EventFilter{
+		KeptnContext: keptnContext,
+		Stage:        stage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch events of stage %s: %w", stage, err)
+	}
+
+	sequenceStart := time.Time{}
+	for _, event := range stageEvents {
+		if !isSequenceTriggeredEvent(event.Event.Type(), stage) {
+			continue
+		}
+
+		if !incomingEvent.Time().IsZero() && event.Time.After(incomingEvent.Time()) {
+			continue
+		}
+
+		if event.Time.After(sequenceStart) {
+			sequenceStart = event.Time
+		}
+	}
+
+	if sequenceStart.IsZero() {
+		return nil, fmt.Errorf("no sequence triggered in stage %s of context %s", stage, keptnContext)
+	}
+
+	scopedFilters := make([]collector.EventFilter, 0, len(filters))
+	for _, filter := range filters {
+		if filter.KeptnContext == keptnContext && filter.Stage == stage && filter.FromTime.IsZero() {
+			filter.FromTime = sequenceStart
+		}
+
+		scopedFilters = append(scopedFilters, filter)
+	}
+
+	return scopedFilters, nil
+}
+
+/**
+ * Checks whether the event type triggers a sequence of the stage, e.g. sh.keptn.event.staging.delivery.triggered.
+ */
+func isSequenceTriggeredEvent(eventType string, stage string) bool {
+	prefix := "sh.keptn.event." + stage + "."
+	if !strings.HasPrefix(eventType, prefix) {
+		return false
+	}
+
+	sequence := strings.TrimPrefix(eventType, prefix)
+	if !strings.HasSuffix(sequence, ".triggered") {
+		return false
+	}
+
+	sequence = strings.TrimSuffix(sequence, ".triggered")
+
+	return sequence != "" && !strings.Contains(sequence, ".")
+}
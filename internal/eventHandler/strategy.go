@@ -0,0 +1,115 @@
+package eventHandler
+
+import (
+	"fmt"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+/**
+ * Builds the filter anchor events of the after strategy are fetched with. Anchors are searched
+ * in the same context, stage and time range as the events of the boundary.
+ */
+func getAnchorFilter(strategy SelectionStrategy, filter collector.EventFilter) (collector.EventFilter, bool, error) {
+	if strategy.Name != collector.StrategyAfter {
+		return collector.EventFilter{}, false, nil
+	}
+
+	err := filter.SetTypes(strategy.Anchor...)
+	if err != nil {
+		return collector.EventFilter{}, false, err
+	}
+
+	return filter, true, nil
+}
+
+/**
+ * Resolves the reference time of a strategy. The after strategy refers to the latest anchor event,
+ * the beforeTriggered strategy to the triggering event.
+ */
+func resolveStrategy(
+	collectorIface collector.CollectorIface,
+	strategy SelectionStrategy,
+	anchorEvents []*collector.EventEnvelope,
+	incomingEvent cloudevents.Event,
+) (collector.SelectionStrategy, error) {
+	resolved := collector.SelectionStrategy{
+		Name: strategy.Name,
+		N:    strategy.N,
+	}
+
+	switch strategy.Name {
+	case collector.StrategyAfter:
+		anchor, err := collectorIface.SelectEvent(anchorEvents, collector.SelectionStrategy{Name: collector.StrategyLast})
+		if err != nil {
+			return collector.SelectionStrategy{}, fmt.Errorf("no anchor event found: %w", err)
+		}
+
+		resolved.Reference = anchor.Time
+	case collector.StrategyBeforeTriggered:
+		if incomingEvent.Time().IsZero() {
+			return collector.SelectionStrategy{}, fmt.Errorf("triggering event has no time")
+		}
+
+		resolved.Reference = incomingEvent.Time()
+	}
+
+	return resolved, nil
+}
+
+/**
+ * Collects the timestamp of a boundary event from its source. If it can't be read from the event
+ * data, the CloudEvent time is used instead and a warning is returned. The source the timestamp was
+ * actually read from is returned alongside.
+ */
+func collectBoundaryTime(
+	boundary string,
+	source collector.TimestampSource,
+	event *collector.EventEnvelope,
+	collectTime func(events []*collector.EventEnvelope, isRounded bool) (time.Time, error),
+) (time.Time, collector.TimestampSource, []string, error) {
+	warnings := []string{}
+
+	if source.Kind != collector.TimestampCloudEvent {
+		timestamp, err := source.Resolve(event)
+		if err == nil {
+			return timestamp, source, warnings, nil
+		}
+
+		warnings = append(warnings, fmt.Sprintf("failed to read %s timestamp from %s, using CloudEvent time: %s", boundary, source.String(), err.Error()))
+	}
+
+	timestamp, err := collectTime([]*collector.EventEnvelope{event}, false)
+
+	return timestamp, collector.TimestampSource{Kind: collector.TimestampCloudEvent}, warnings, err
+}
+
+func newBoundarySelection(
+	strategy SelectionStrategy,
+	event *collector.EventEnvelope,
+	timestamp time.Time,
+	source collector.TimestampSource,
+	rounding collector.Rounding,
+) BoundarySelection {
+	return BoundarySelection{
+		Strategy:        strategy.String(),
+		EventId:         event.Event.ID(),
+		EventType:       event.Event.Type(),
+		Time:            timestamp.Format(time.RFC3339Nano),
+		TimestampSource: source.String(),
+		Rounding:        rounding.String(),
+	}
+}
+
+/**
+ * Describes a boundary that wasn't taken from an event, e.g. now or duration 1h0m0s.
+ */
+func newRelativeBoundarySelection(mode string, timestamp time.Time, rounding collector.Rounding) BoundarySelection {
+	return BoundarySelection{
+		Strategy: mode,
+		Time:     timestamp.Format(time.RFC3339Nano),
+		Rounding: rounding.String(),
+	}
+}
@@ -0,0 +1,61 @@
+package eventHandler
+
+import (
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+/**
+ * Builds the filter phase marker events are fetched with. Markers are searched in the same
+ * context, stage and time range as the evaluation start events.
+ */
+func getMarkerFilter(trim PhaseTrim, filter collector.EventFilter) (collector.EventFilter, bool, error) {
+	if !trim.HasMarker() {
+		return collector.EventFilter{}, false, nil
+	}
+
+	err := filter.SetTypes(trim.MarkerTypes...)
+	if err != nil {
+		return collector.EventFilter{}, false, err
+	}
+
+	return filter, true, nil
+}
+
+/**
+ * Restricts marker events to the ones carrying the label of the trim, if any.
+ */
+func selectMarkers(trim PhaseTrim, events []*collector.EventEnvelope) []*collector.EventEnvelope {
+	if trim.Label == "" {
+		return events
+	}
+
+	return collector.SelectByLabel(events, trim.Label, trim.Value)
+}
+
+/**
+ * Combines the trims of both phases. Marker events are taken from the fetched events.
+ */
+func newWindowTrims(
+	warmUpTrim PhaseTrim,
+	coolDownTrim PhaseTrim,
+	eventsByFilter map[collector.EventFilter][]*collector.EventEnvelope,
+	warmUpMarkerFilter collector.EventFilter,
+	coolDownMarkerFilter collector.EventFilter,
+) collector.WindowTrims {
+	trims := collector.WindowTrims{
+		WarmUp:            warmUpTrim.Duration,
+		CoolDown:          coolDownTrim.Duration,
+		HasWarmUpMarker:   warmUpTrim.HasMarker(),
+		HasCoolDownMarker: coolDownTrim.HasMarker(),
+	}
+
+	if trims.HasWarmUpMarker {
+		trims.WarmUpMarkers = selectMarkers(warmUpTrim, eventsByFilter[warmUpMarkerFilter])
+	}
+
+	if trims.HasCoolDownMarker {
+		trims.CoolDownMarkers = selectMarkers(coolDownTrim, eventsByFilter[coolDownMarkerFilter])
+	}
+
+	return trims
+}
@@ -0,0 +1,445 @@
+package eventHandler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/keptn-sandbox/keptn-test-collector-service/internal/collector"
+)
+
+// windowCollection is an evaluation window collected for one set of collection options, along with
+// the warnings raised and the events fetched while collecting it. The additional filters are scoped
+// like the boundary filters and key their events.
+type windowCollection struct {
+	window            CollectedWindowData
+	warnings          []string
+	additionalFilters []collector.EventFilter
+	eventsByFilter    map[collector.EventFilter][]*collector.EventEnvelope
+}
+
+/**
+ * Collects the evaluation window described by the collection options: boundary events are fetched
+ * and selected, and their timestamps rounded, validated, trimmed and constrained. The additional
+ * filters are scoped and fetched alongside the boundary events. Errors are returned as is, callers
+ * report them as aborting the collection.
+ */
+func collectWindow(
+	ctx context.Context,
+	collectorIface collector.CollectorIface,
+	incomingEvent cloudevents.Event,
+	collectionEventDataIface CollectionEventDataIface,
+	now time.Time,
+	additionalFilters ...collector.EventFilter,
+) (windowCollection, error) {
+	eventData := collectionEventDataIface.GetEventData()
+
+	collectionStartFilter, err := collectionEventDataIface.GetEvaluationStartFilter(now)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	collectionEndFilter, err := collectionEventDataIface.GetEvaluationEndFilter(now)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationStartExpression, err := compileExpression("evaluation start", collectionEventDataIface.GetEvaluationStartExpression())
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationEndExpression, err := compileExpression("evaluation end", collectionEventDataIface.GetEvaluationEndExpression())
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationStartStrategy, err := collectionEventDataIface.GetEvaluationStartStrategy()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationEndStrategy, err := collectionEventDataIface.GetEvaluationEndStrategy()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationStartRounding, err := collectionEventDataIface.GetEvaluationStartRounding()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationEndRounding, err := collectionEventDataIface.GetEvaluationEndRounding()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationStartTimestampSource, err := collectionEventDataIface.GetEvaluationStartTimestampSource()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationEndTimestampSource, err := collectionEventDataIface.GetEvaluationEndTimestampSource()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationStartMode, err := collectionEventDataIface.GetEvaluationStartMode()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationEndMode, err := collectionEventDataIface.GetEvaluationEndMode()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	evaluationDuration, err := collectionEventDataIface.GetEvaluationDuration()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	windowConstraints, err := collectionEventDataIface.GetWindowConstraints()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	warmUpTrim, err := collectionEventDataIface.GetWarmUpTrim()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	coolDownTrim, err := collectionEventDataIface.GetCoolDownTrim()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	windowSplit, err := collectionEventDataIface.GetWindowSplit()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	windowValidation, err := collectionEventDataIface.GetWindowValidation()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	scope, err := collectionEventDataIface.GetScope()
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	if scope == ScopeSequence && eventData.Stage != "" {
+		unscopedFilters := append([]collector.EventFilter{collectionStartFilter, collectionEndFilter}, additionalFilters...)
+		scopedFilters, err := scopeToSequence(ctx, collectorIface, incomingEvent, eventData.Stage, unscopedFilters...)
+		if err != nil {
+			return windowCollection{}, err
+		}
+
+		collectionStartFilter, collectionEndFilter = scopedFilters[0], scopedFilters[1]
+		additionalFilters = scopedFilters[2:]
+	}
+
+	filters := append([]collector.EventFilter{}, additionalFilters...)
+
+	// Events of relative boundaries are not fetched
+	evaluationStartAnchorFilter := collector.EventFilter{}
+	if evaluationStartMode == ModeEvent {
+		filters = append(filters, collectionStartFilter)
+
+		var hasEvaluationStartAnchor bool
+		evaluationStartAnchorFilter, hasEvaluationStartAnchor, err = getAnchorFilter(evaluationStartStrategy, collectionStartFilter)
+		if err != nil {
+			return windowCollection{}, err
+		}
+
+		if hasEvaluationStartAnchor {
+			filters = append(filters, evaluationStartAnchorFilter)
+		}
+	}
+
+	evaluationEndAnchorFilter := collector.EventFilter{}
+	if evaluationEndMode == ModeEvent {
+		filters = append(filters, collectionEndFilter)
+
+		var hasEvaluationEndAnchor bool
+		evaluationEndAnchorFilter, hasEvaluationEndAnchor, err = getAnchorFilter(evaluationEndStrategy, collectionEndFilter)
+		if err != nil {
+			return windowCollection{}, err
+		}
+
+		if hasEvaluationEndAnchor {
+			filters = append(filters, evaluationEndAnchorFilter)
+		}
+	}
+
+	warmUpMarkerFilter, hasWarmUpMarker, err := getMarkerFilter(warmUpTrim, collectionStartFilter)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	if hasWarmUpMarker {
+		filters = append(filters, warmUpMarkerFilter)
+	}
+
+	coolDownMarkerFilter, hasCoolDownMarker, err := getMarkerFilter(coolDownTrim, collectionStartFilter)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	if hasCoolDownMarker {
+		filters = append(filters, coolDownMarkerFilter)
+	}
+
+	eventsByFilter, decodeWarnings, err := fetchFilteredEvents(ctx, collectorIface, filters)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	warnings := []string{}
+	for _, decodeWarning := range decodeWarnings {
+		warnings = append(warnings, decodeWarning.String())
+	}
+
+	isEvaluationStartRelative := evaluationStartMode == ModeDuration
+
+	var evaluationStartEvents []*collector.EventEnvelope
+	if !isEvaluationStartRelative {
+		var expressionWarnings []string
+		evaluationStartEvents, expressionWarnings = selectByExpression(evaluationStartExpression, eventsByFilter[collectionStartFilter])
+		warnings = append(warnings, expressionWarnings...)
+	}
+
+	var evaluationEndEvents []*collector.EventEnvelope
+	if evaluationEndMode == ModeEvent {
+		var expressionWarnings []string
+		evaluationEndEvents, expressionWarnings = selectByExpression(evaluationEndExpression, eventsByFilter[collectionEndFilter])
+		warnings = append(warnings, expressionWarnings...)
+	}
+
+	evaluationStartEvents, evaluationEndEvents, err = selectBoundaryExecutions(
+		collectorIface,
+		evaluationStartEvents, collectionEventDataIface.GetEvaluationStartExecution(), collectionStartFilter,
+		evaluationEndEvents, collectionEventDataIface.GetEvaluationEndExecution(), collectionEndFilter,
+	)
+	if err != nil {
+		return windowCollection{}, err
+	}
+
+	// Evaluation start is earliest event timestamp, unless it is relative to the end
+	var evaluationStartEvent *collector.EventEnvelope
+	var unroundedEvaluationStart time.Time
+
+	if !isEvaluationStartRelative {
+		if len(evaluationStartEvents) == 0 && evaluationDuration > 0 {
+			isEvaluationStartRelative = true
+			warnings = append(warnings, fmt.Sprintf("no start event found for %s, starting evaluation %s before its end", collectionStartFilter.String(), evaluationDuration))
+		} else {
+			resolvedStartStrategy, err := resolveStrategy(collectorIface, evaluationStartStrategy, eventsByFilter[evaluationStartAnchorFilter], incomingEvent)
+			if err != nil {
+				return windowCollection{}, fmt.Errorf("Failed to resolve start strategy %s for %s: %s", evaluationStartStrategy.String(), collectionStartFilter.String(), err.Error())
+			}
+
+			evaluationStartEvent, err = collectorIface.SelectEvent(evaluationStartEvents, resolvedStartStrategy)
+			if err != nil {
+				return windowCollection{}, fmt.Errorf("Failed to collect start timestamps for %s: %s", collectionStartFilter.String(), err.Error())
+			}
+
+			var timestampWarnings []string
+			unroundedEvaluationStart, evaluationStartTimestampSource, timestampWarnings, err = collectBoundaryTime("start", evaluationStartTimestampSource, evaluationStartEvent, collectorIface.CollectEarliestTime)
+			warnings = append(warnings, timestampWarnings...)
+			if err != nil {
+				return windowCollection{}, fmt.Errorf("Failed to collect start timestamps for %s: %s", collectionStartFilter.String(), err.Error())
+			}
+		}
+	}
+
+	// Evaluation end is latest event timestamp, unless it is relative to the collection
+	var evaluationEndEvent *collector.EventEnvelope
+	var unroundedEvaluationEnd time.Time
+
+	switch evaluationEndMode {
+	case ModeNow:
+		unroundedEvaluationEnd = now
+	case ModeTriggered:
+		if incomingEvent.Time().IsZero() {
+			return windowCollection{}, fmt.Errorf("Failed to collect end timestamp: triggering event has no time")
+		}
+
+		unroundedEvaluationEnd = incomingEvent.Time()
+	default:
+		resolvedEndStrategy, err := resolveStrategy(collectorIface, evaluationEndStrategy, eventsByFilter[evaluationEndAnchorFilter], incomingEvent)
+		if err != nil {
+			return windowCollection{}, fmt.Errorf("Failed to resolve end strategy %s for %s: %s", evaluationEndStrategy.String(), collectionEndFilter.String(), err.Error())
+		}
+
+		evaluationEndEvent, err = collectorIface.SelectEvent(evaluationEndEvents, resolvedEndStrategy)
+		if err != nil {
+			return windowCollection{}, fmt.Errorf("Failed to collect end timestamps for %s: %s", collectionEndFilter.String(), err.Error())
+		}
+
+		var timestampWarnings []string
+		unroundedEvaluationEnd, evaluationEndTimestampSource, timestampWarnings, err = collectBoundaryTime("end", evaluationEndTimestampSource, evaluationEndEvent, collectorIface.CollectLatestTime)
+		warnings = append(warnings, timestampWarnings...)
+		if err != nil {
+			return windowCollection{}, fmt.Errorf("Failed to collect end timestamps for %s: %s", collectionEndFilter.String(), err.Error())
+		}
+	}
+
+	evaluationEnd := evaluationEndRounding.Apply(unroundedEvaluationEnd)
+	evaluationEndSelection := newRelativeBoundarySelection(evaluationEndMode, unroundedEvaluationEnd, evaluationEndRounding)
+	if evaluationEndEvent != nil {
+		evaluationEndSelection = newBoundarySelection(evaluationEndStrategy, evaluationEndEvent, unroundedEvaluationEnd, evaluationEndTimestampSource, evaluationEndRounding)
+	}
+
+	// Relative starts keep the evaluation duration exact by inheriting the rounding of the end
+	evaluationStart := evaluationStartRounding.Apply(unroundedEvaluationStart)
+	var evaluationStartSelection BoundarySelection
+	if isEvaluationStartRelative {
+		unroundedEvaluationStart = unroundedEvaluationEnd.Add(-evaluationDuration)
+		evaluationStart = evaluationEnd.Add(-evaluationDuration)
+		evaluationStartSelection = newRelativeBoundarySelection(fmt.Sprintf("%s %s", ModeDuration, evaluationDuration), unroundedEvaluationStart, evaluationEndRounding)
+	} else {
+		evaluationStartSelection = newBoundarySelection(evaluationStartStrategy, evaluationStartEvent, unroundedEvaluationStart, evaluationStartTimestampSource, evaluationStartRounding)
+	}
+
+	rawEvaluationWindow := collector.Window{
+		Start: evaluationStart,
+		End:   evaluationEnd,
+	}
+
+	// Validated before trims and constraints, which could otherwise turn an inverted window into a valid one
+	windowProblems := windowValidation.Validate(collector.Window{Start: unroundedEvaluationStart, End: unroundedEvaluationEnd}, rawEvaluationWindow, now)
+	if len(windowProblems) > 0 && windowValidation.Policy == collector.ValidationFail {
+		return windowCollection{}, fmt.Errorf("Invalid evaluation window: %s", strings.Join(windowProblems, "; "))
+	}
+
+	warnings = append(warnings, windowProblems...)
+
+	windowTrims := newWindowTrims(warmUpTrim, coolDownTrim, eventsByFilter, warmUpMarkerFilter, coolDownMarkerFilter)
+
+	trimmedEvaluationWindow, trimAdjustments, err := windowTrims.Apply(rawEvaluationWindow)
+	if err != nil {
+		return windowCollection{}, fmt.Errorf("Failed to trim evaluation window: %s", err.Error())
+	}
+
+	evaluationWindow, constraintAdjustments, err := windowConstraints.Apply(trimmedEvaluationWindow)
+	if err != nil {
+		return windowCollection{}, fmt.Errorf("Failed to adjust evaluation window: %s", err.Error())
+	}
+
+	adjustments := append(trimAdjustments, constraintAdjustments...)
+
+	// The raw window is only reported if it differs from the trimmed one
+	var rawEvaluation *WindowData
+	if len(trimAdjustments) > 0 {
+		rawEvaluation = &WindowData{
+			Start: rawEvaluationWindow.Start.Format(time.RFC3339),
+			End:   rawEvaluationWindow.End.Format(time.RFC3339),
+		}
+	}
+
+	subWindows, err := windowSplit.Apply(evaluationWindow)
+	if err != nil {
+		return windowCollection{}, fmt.Errorf("Failed to split evaluation window: %s", err.Error())
+	}
+
+	var subWindowData []EvaluationData
+	for _, subWindow := range subWindows {
+		subWindowData = append(subWindowData, newEvaluationData(subWindow))
+	}
+
+	return windowCollection{
+		window: CollectedWindowData{
+			Evaluation:    newEvaluationData(evaluationWindow),
+			SubWindows:    subWindowData,
+			RawEvaluation: rawEvaluation,
+			UnroundedEvaluation: WindowData{
+				Start: unroundedEvaluationStart.Format(time.RFC3339Nano),
+				End:   unroundedEvaluationEnd.Format(time.RFC3339Nano),
+			},
+			Selection: SelectionData{
+				Start: evaluationStartSelection,
+				End:   evaluationEndSelection,
+			},
+			Adjustments: adjustments,
+		},
+		warnings:          warnings,
+		additionalFilters: additionalFilters,
+		eventsByFilter:    eventsByFilter,
+	}, nil
+}
+
+/**
+ * Restricts the start and end events to their executions. If both boundaries select the same
+ * execution, it is resolved once from the events of both, so that e.g. the latest execution can't
+ * provide the start of a retry and the end of the run before it.
+ */
+func selectBoundaryExecutions(
+	collectorIface collector.CollectorIface,
+	startEvents []*collector.EventEnvelope,
+	startSelector string,
+	startFilter collector.EventFilter,
+	endEvents []*collector.EventEnvelope,
+	endSelector string,
+	endFilter collector.EventFilter,
+) ([]*collector.EventEnvelope, []*collector.EventEnvelope, error) {
+	if startSelector == "" || startSelector != endSelector || len(startEvents) == 0 || len(endEvents) == 0 {
+		startEvents, err := selectExecution(collectorIface, startEvents, startSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to select start execution for %s: %s", startFilter.String(), err.Error())
+		}
+
+		endEvents, err := selectExecution(collectorIface, endEvents, endSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to select end execution for %s: %s", endFilter.String(), err.Error())
+		}
+
+		return startEvents, endEvents, nil
+	}
+
+	boundaryEvents := append(append([]*collector.EventEnvelope{}, startEvents...), endEvents...)
+	executionEvents, err := selectExecution(collectorIface, boundaryEvents, startSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to select execution for %s and %s: %s", startFilter.String(), endFilter.String(), err.Error())
+	}
+
+	isExecutionEvent := map[*collector.EventEnvelope]bool{}
+	for _, event := range executionEvents {
+		isExecutionEvent[event] = true
+	}
+
+	startEvents = filterEnvelopes(startEvents, isExecutionEvent)
+	endEvents = filterEnvelopes(endEvents, isExecutionEvent)
+
+	// Missing start events may still fall back to the evaluation duration
+	if len(endEvents) == 0 {
+		return nil, nil, fmt.Errorf("Failed to select execution for %s and %s: execution \"%s\" holds no end events", startFilter.String(), endFilter.String(), startSelector)
+	}
+
+	return startEvents, endEvents, nil
+}
+
+/**
+ * Returns the events contained in the set, keeping their order.
+ */
+func filterEnvelopes(events []*collector.EventEnvelope, contained map[*collector.EventEnvelope]bool) []*collector.EventEnvelope {
+	filtered := []*collector.EventEnvelope{}
+	for _, event := range events {
+		if contained[event] {
+			filtered = append(filtered, event)
+		}
+	}
+
+	return filtered
+}
+
+func newEvaluationData(window collector.Window) EvaluationData {
+	return EvaluationData{
+		Start:     window.Start.Format(time.RFC3339),
+		End:       window.End.Format(time.RFC3339),
+		Timeframe: window.Timeframe(),
+	}
+}
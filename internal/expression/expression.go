@@ -0,0 +1,184 @@
+// Package expression evaluates side-effect-free CEL (https://github.com/google/cel-spec)
+// expressions against a set of variables, e.g.
+//
+//	type.endsWith(".finished") && data.result == "pass" && data.labels["team"] in ["a", "b"]
+//
+// The environment is restricted: variables are dynamically typed and shadow standard identifiers
+// of the same name, e.g. type. The only macro is has(), so expressions can't loop with all(),
+// exists(), map() or filter(), and the size, nesting depth and evaluation cost of expressions are
+// bounded.
+package expression
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker"
+	"github.com/google/cel-go/common"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/interpreter"
+	"github.com/google/cel-go/interpreter/functions"
+	"github.com/google/cel-go/parser"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+)
+
+// Limits of expressions
+const (
+	maxSourceLength   = 1024
+	maxNestingDepth   = 32
+	maxEvaluationCost = 10000
+)
+
+// Program is a compiled expression.
+type Program struct {
+	source  string
+	program cel.Program
+}
+
+/**
+ * Compiles an expression. Only the given variables may be referenced.
+ */
+func Compile(source string, variables ...string) (*Program, error) {
+	if len(source) > maxSourceLength {
+		return nil, fmt.Errorf("expression exceeds %d characters", maxSourceLength)
+	}
+
+	p, err := parser.NewParser(
+		parser.Macros(parser.HasMacro),
+		parser.ExpressionSizeCodePointLimit(maxSourceLength),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	text := common.NewTextSource(source)
+
+	parsed, errs := p.Parse(text)
+	if len(errs.GetErrors()) > 0 {
+		return nil, formatErrors(text, errs.GetErrors())
+	}
+
+	if nestingDepth(parsed.GetExpr()) > maxNestingDepth {
+		return nil, fmt.Errorf("expression nested deeper than %d levels", maxNestingDepth)
+	}
+
+	declared := map[string]bool{}
+	options := []cel.EnvOption{cel.CrossTypeNumericComparisons(true)}
+	for _, variable := range variables {
+		declared[variable] = true
+		options = append(options, cel.Variable(variable, cel.DynType))
+	}
+
+	// Variables shadow standard identifiers of the same name, e.g. the type() conversion
+	standardDeclarations := []*exprpb.Decl{}
+	for _, declaration := range checker.StandardDeclarations() {
+		if !declared[declaration.GetName()] {
+			standardDeclarations = append(standardDeclarations, declaration)
+		}
+	}
+
+	options = append(options, cel.Declarations(standardDeclarations...))
+
+	env, err := cel.NewCustomEnv(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	checked, issues := env.Check(cel.ParsedExprToAst(parsed))
+	if issues != nil && issues.Err() != nil {
+		return nil, formatErrors(text, issues.Errors())
+	}
+
+	if outputType := checked.OutputType(); outputType != cel.BoolType && outputType != cel.DynType {
+		return nil, fmt.Errorf("expression evaluates to %s instead of bool", outputType)
+	}
+
+	// Compiles constant patterns of matches() once, which also reports invalid ones here
+	program, err := env.Program(
+		checked,
+		cel.Functions(functions.StandardOverloads()...),
+		cel.CostLimit(maxEvaluationCost),
+		cel.OptimizeRegex(interpreter.MatchesRegexOptimization),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Program{source: source, program: program}, nil
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+/**
+ * Evaluates the expression against the variables. Expressions that don't evaluate to a boolean
+ * result in an error.
+ */
+func (p *Program) Evaluate(variables map[string]interface{}) (bool, error) {
+	result, _, err := p.program.Eval(variables)
+	if err != nil {
+		return false, err
+	}
+
+	isMatching, ok := result.(types.Bool)
+	if !ok {
+		return false, fmt.Errorf("expression evaluated to %s instead of bool", result.Type().TypeName())
+	}
+
+	return bool(isMatching), nil
+}
+
+/**
+ * Formats errors of the expression with their position if known, e.g. "undeclared reference to
+ * 'result' at position 0".
+ */
+func formatErrors(source common.Source, errs []common.Error) error {
+	messages := []string{}
+	for _, err := range errs {
+		message := strings.TrimSuffix(err.Message, " (in container '')")
+		if offset, ok := source.LocationOffset(err.Location); ok && err.Location.Column() >= 0 {
+			message = fmt.Sprintf("%s at position %d", message, offset)
+		}
+
+		messages = append(messages, message)
+	}
+
+	return fmt.Errorf("%s", strings.Join(messages, "; "))
+}
+
+/**
+ * Returns the depth of the expression tree, e.g. 3 for data.result == "pass".
+ */
+func nestingDepth(expr *exprpb.Expr) int {
+	children := []*exprpb.Expr{}
+
+	switch kind := expr.GetExprKind().(type) {
+	case *exprpb.Expr_SelectExpr:
+		children = append(children, kind.SelectExpr.GetOperand())
+	case *exprpb.Expr_CallExpr:
+		if kind.CallExpr.GetTarget() != nil {
+			children = append(children, kind.CallExpr.GetTarget())
+		}
+		children = append(children, kind.CallExpr.GetArgs()...)
+	case *exprpb.Expr_ListExpr:
+		children = append(children, kind.ListExpr.GetElements()...)
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range kind.StructExpr.GetEntries() {
+			if entry.GetMapKey() != nil {
+				children = append(children, entry.GetMapKey())
+			}
+			children = append(children, entry.GetValue())
+		}
+	}
+
+	depth := 0
+	for _, child := range children {
+		if childDepth := nestingDepth(child); childDepth > depth {
+			depth = childDepth
+		}
+	}
+
+	return depth + 1
+}
@@ -0,0 +1,138 @@
+package expression
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func newTestVariables() map[string]interface{} {
+	return map[string]interface{}{
+		"type":   "sh.keptn.event.test.finished",
+		"source": "jmeter-service",
+		"time":   nil,
+		"data": map[string]interface{}{
+			"result": "pass",
+			"labels": map[string]interface{}{"team": "checkout", "build-id": "42"},
+			"test":   map[string]interface{}{"name": "load", "users": float64(100), "passed": true},
+			"tags":   []interface{}{"smoke", "nightly"},
+		},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected bool
+	}{
+		{name: "equal", source: `type == "sh.keptn.event.test.finished"`, expected: true},
+		{name: "not equal", source: `data.result != "pass"`, expected: false},
+		{name: "less", source: `data.test.users < 100`, expected: false},
+		{name: "less or equal", source: `data.test.users <= 100`, expected: true},
+		{name: "greater", source: `data.test.users > 99.5`, expected: true},
+		{name: "greater or equal", source: `data.test.users >= 101`, expected: false},
+		{name: "string comparison", source: `data.result < "skip"`, expected: true},
+		{name: "and", source: `data.result == "pass" && data.test.passed`, expected: true},
+		{name: "or", source: `source == 'locust-service' || data.result == "pass"`, expected: true},
+		{name: "not", source: `!(data.result != "pass")`, expected: true},
+		{name: "negation", source: `-data.test.users < 0`, expected: true},
+		{name: "arithmetic", source: `data.test.users * 2.0 + 1.0 == 201.0`, expected: true},
+		{name: "conditional", source: `(data.result == "pass" ? data.test.users : 0.0) == 100`, expected: true},
+		{name: "precedence of and over or", source: `true || false && false`, expected: true},
+		{name: "precedence of comparison over and", source: `1 < 2 && 2 < 3`, expected: true},
+		{name: "in list", source: `data.labels.team in ["checkout", "payment"]`, expected: true},
+		{name: "in list field", source: `"nightly" in data.tags`, expected: true},
+		{name: "in map", source: `"team" in data.labels && !("owner" in data.labels)`, expected: true},
+		{name: "index", source: `data.labels["build-id"] == "42" && data.tags[1] == "nightly"`, expected: true},
+		{name: "has", source: `has(data.test.name) && !has(data.test.duration)`, expected: true},
+		{name: "size", source: `size(data.tags) == 2 && data.test.name.size() == 4`, expected: true},
+		{name: "starts with", source: `type.startsWith("sh.keptn.event.deployment")`, expected: false},
+		{name: "ends with", source: `type.endsWith(".finished")`, expected: true},
+		{name: "contains", source: `data.test.name.contains("stress")`, expected: false},
+		{name: "matches", source: `data.test.name.matches("^lo+ad$")`, expected: true},
+		{name: "null", source: `time == null`, expected: true},
+		{name: "or skips errors if true", source: `data.missing || true`, expected: true},
+		{name: "and skips errors if false", source: `data.result == "fail" && data.missing`, expected: false},
+		{name: "nested within limits", source: strings.Repeat("[", maxNestingDepth-1) + strings.Repeat("]", maxNestingDepth-1) + ` != []`, expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := Compile(test.source, "type", "source", "time", "data")
+			assert.NilError(t, err)
+
+			result, err := program.Evaluate(newTestVariables())
+			assert.NilError(t, err)
+			assert.Equal(t, result, test.expected)
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		variables     map[string]interface{}
+		expectedError string
+	}{
+		{name: "no bool", source: `data.result`, expectedError: "expression evaluated to string instead of bool"},
+		{name: "missing field", source: `data.missing == "x"`, expectedError: "no such key: missing"},
+		{name: "field of string", source: `data.result.field == "x"`, expectedError: "no such key: field"},
+		{name: "index out of bounds", source: `data.tags[5] == "x"`, expectedError: "index out of bounds: 5"},
+		{name: "compare number with string", source: `data.test.users > "10"`, expectedError: "no such overload"},
+		{name: "and of number", source: `data.test.users && true`, expectedError: "no such overload"},
+		{name: "division by zero", source: `int(data.test.users) / 0 == 1`, expectedError: "division by zero"},
+		{name: "invalid dynamic pattern", source: `data.result.matches(data.result + "(")`, expectedError: "error parsing regexp: missing closing ): `pass(`"},
+		{
+			name:          "cost limit",
+			source:        `data.result.contains("x")`,
+			variables:     map[string]interface{}{"data": map[string]interface{}{"result": strings.Repeat("a", 10*maxEvaluationCost)}},
+			expectedError: "operation cancelled: actual cost limit exceeded",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			program, err := Compile(test.source, "data")
+			assert.NilError(t, err)
+
+			if test.variables == nil {
+				test.variables = newTestVariables()
+			}
+
+			_, err = program.Evaluate(test.variables)
+			assert.Error(t, err, test.expectedError)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		source        string
+		expectedError string
+	}{
+		{name: "undeclared variable", source: `result == "pass"`, expectedError: "undeclared reference to 'result' at position 0"},
+		{name: "undeclared function", source: `lower(data.result) == "pass"`, expectedError: "undeclared reference to 'lower' at position 5"},
+		{name: "wrong argument type", source: `data.result.startsWith(1)`, expectedError: "found no matching overload for 'startsWith' applied to 'dyn.(int)' at position 22"},
+		{name: "mismatching literals", source: `"a" == 1`, expectedError: "found no matching overload for '_==_' applied to '(string, int)' at position 4"},
+		{name: "no bool", source: `1 + 2`, expectedError: "expression evaluates to int instead of bool"},
+		{name: "invalid constant pattern", source: `data.result.matches("(")`, expectedError: "error parsing regexp: missing closing ): `(`"},
+		{name: "has without field", source: `has(data)`, expectedError: "invalid argument to has() macro at position 3"},
+		{name: "exists macro disabled", source: `data.tags.exists(t, t == "a")`, expectedError: "undeclared reference to 'exists' at position 16; undeclared reference to 't' at position 17; undeclared reference to 't' at position 20"},
+		{name: "trailing token", source: `data.result == "pass" data`, expectedError: "Syntax error: extraneous input 'data' expecting <EOF> at position 22"},
+		{name: "position on later line", source: "data.result == \"pass\" &&\n  result", expectedError: "undeclared reference to 'result' at position 27"},
+		{name: "nesting limit", source: strings.Repeat("[", maxNestingDepth) + strings.Repeat("]", maxNestingDepth) + ` != []`, expectedError: "expression nested deeper than 32 levels"},
+		{name: "parser recursion limit", source: strings.Repeat("(", 300) + "true" + strings.Repeat(")", 300), expectedError: "expression recursion limit exceeded: 250"},
+		{name: "length limit", source: `data.result == "` + strings.Repeat("a", maxSourceLength) + `"`, expectedError: "expression exceeds 1024 characters"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Compile(test.source, "data")
+			assert.Error(t, err, test.expectedError)
+		})
+	}
+}
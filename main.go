@@ -21,6 +21,8 @@ import (
 
 var keptnOptions = keptn.KeptnOpts{}
 
+var eventSource collector.EventSource
+
 // type gracefulShutdownKeyType struct{}
 
 // Opaque key type used for graceful shutdown context value
@@ -122,14 +124,21 @@ func processKeptnCloudEvent(ctx context.Context, event cloudevents.Event) error
 	case keptnv2.GetTriggeredEventType("collection"):
 		log.Printf("Processing collection.triggered Event")
 
-		collectorIface := collector.NewCollector()
+		collectorIface := collector.NewCollector(eventSource)
 
 		eventDataHandlerIface, err := eventHandler.NewEventDataHandler(event)
 		if err != nil {
-			return err
+			return eventHandler.InvalidCollectionCloudEventHandler(myKeptn, event, ServiceName, err)
 		}
 
-		return eventHandler.CollectionCloudEventHandler(myKeptn, event, ServiceName, collectorIface, eventDataHandlerIface)
+		err = eventHandler.CollectionCloudEventHandler(ctx, myKeptn, event, ServiceName, collectorIface, eventDataHandlerIface)
+
+		if eventCache, ok := eventSource.(*collector.CachingEventSource); ok {
+			stats := eventCache.Stats()
+			log.Printf("Event cache: %d hits, %d misses, %d entries, %d bytes", stats.Hits, stats.Misses, stats.Entries, stats.SizeBytes)
+		}
+
+		return err
 	}
 
 	// Unknown Event -> Throw Error!
@@ -167,10 +176,20 @@ func _main(args []string, env envConfig) int {
 
 	keptnOptions.ConfigurationServiceURL = env.ConfigurationServiceUrl
 
+	var err error
+	eventSource, err = collector.NewEventSource()
+	if err != nil {
+		log.Fatalf("failed to create event source, %v", err)
+	}
+
+	eventSource = collector.NewCachingEventSourceFromEnv(eventSource)
+
 	log.Printf("Starting %s...", ServiceName)
 	log.Printf("    on Port = %d; Path=%s", env.Port, env.Path)
 
-	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	// Cancelling the receiver context on shutdown also cancels in-flight collections
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	log.Printf("Creating new http handler")
 